@@ -0,0 +1,118 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"math"
+	"runtime"
+
+	"github.com/dvaumoron/shelltools/pkg/cmdproxy"
+)
+
+// conservative limits, same default as xargs (ARG_MAX is at least 128 KiB on unix-like systems, environment excluded)
+const (
+	defaultMaxChars = 128*1024 - 2048
+	windowsMaxChars = 32767
+)
+
+var errArgTooLong = errors.New("argument line too long")
+
+// pack items (arguments computed from one line) in as few CMD calls as possible
+type batcher struct {
+	run      func(string, []string, []string) error
+	cmdName  string
+	cmdArgs  []string
+	inputs   []string
+	baseLen  int
+	baseSize int
+	size     int
+	count    int
+	done     int
+	maxCount int
+	maxChars int
+}
+
+func newBatcher(run func(string, []string, []string) error, cmdName string, prefix []string) *batcher {
+	maxCount, maxChars := batchSize, maxCharsOption
+	switch {
+	case maxCount == 0 && maxChars == 0:
+		maxCount, maxChars = 1, math.MaxInt
+	case maxChars == 0:
+		maxChars = defaultMaxChars
+		if runtime.GOOS == "windows" {
+			maxChars = windowsMaxChars
+		}
+	}
+
+	cmdArgs := append([]string(nil), prefix...)
+	baseSize := argsSize([]string{cmdName}) + argsSize(prefix)
+	return &batcher{
+		run: run, cmdName: cmdName, cmdArgs: cmdArgs, baseLen: len(cmdArgs), baseSize: baseSize,
+		size: baseSize, maxCount: maxCount, maxChars: maxChars,
+	}
+}
+
+func (b *batcher) add(line string, item []string) error {
+	itemSize := argsSize(item)
+	if b.baseSize+itemSize > b.maxChars {
+		return errArgTooLong
+	}
+
+	if b.count != 0 && b.size+itemSize > b.maxChars {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+
+	b.cmdArgs = append(b.cmdArgs, item...)
+	b.inputs = append(b.inputs, line)
+	b.size += itemSize
+	b.count++
+	if b.count == b.maxCount {
+		return b.flush()
+	}
+	return nil
+}
+
+func (b *batcher) flush() error {
+	if b.count == 0 {
+		return nil
+	}
+
+	err := b.run(b.cmdName, b.cmdArgs, b.inputs)
+
+	if !cmdproxy.Stopped() { // an interrupted call does not count as processed
+		b.done += b.count
+	}
+	b.cmdArgs = b.cmdArgs[:b.baseLen]
+	b.inputs = b.inputs[:0]
+	b.size = b.baseSize
+	b.count = 0
+	return err
+}
+
+// count a terminating zero for each argument (like in argv)
+func argsSize(args []string) int {
+	size := 0
+	for _, arg := range args {
+		size += len(arg) + 1
+	}
+	return size
+}
@@ -0,0 +1,154 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dvaumoron/shelltools/pkg/cmdproxy"
+)
+
+const safeShellChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-"
+
+var errQuit = errors.New("quit asked")
+
+func dryRunCommand(cmdName string, cmdArgs []string, inputs []string) error {
+	command, err := describeCommand(cmdName, cmdArgs, inputs)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Print(command, lineOptions.Delimiter())
+	return err
+}
+
+// ask before each call, reading answers from terminal (standard input could be FILE)
+type confirmer struct {
+	reader *bufio.Reader
+	all    bool
+	run    func(string, []string, []string) error
+}
+
+func (c *confirmer) confirmCommand(cmdName string, cmdArgs []string, inputs []string) error {
+	for !c.all {
+		command, err := describeCommand(cmdName, cmdArgs, inputs)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprint(os.Stderr, command, " ? [y/n/a/q] ")
+		answer, ok := c.readAnswer()
+		if cmdproxy.Stopped() {
+			fmt.Fprintln(os.Stderr)
+			return nil // interruption is reported by the caller
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr)
+			return errQuit
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return c.run(cmdName, cmdArgs, inputs)
+		case "n", "no":
+			return nil
+		case "a", "all":
+			c.all = true
+		case "q", "quit":
+			return errQuit
+		}
+	}
+	return c.run(cmdName, cmdArgs, inputs)
+}
+
+// the read is abandoned when a signal is received (false is returned on end of input too)
+func (c *confirmer) readAnswer() (string, bool) {
+	answers := make(chan string, 1)
+	go func() {
+		answer, err := c.reader.ReadString('\n')
+		if err != nil && answer == "" {
+			close(answers)
+			return
+		}
+		answers <- answer
+	}()
+
+	select {
+	case answer, ok := <-answers:
+		return answer, ok
+	case <-cmdproxy.Done():
+		return "", false
+	}
+}
+
+// shell equivalent of the call, with its directory, environment and input
+func describeCommand(cmdName string, cmdArgs []string, inputs []string) (string, error) {
+	job, err := jobRunner(inputs)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if job.Dir != "" {
+		builder.WriteString("cd ")
+		builder.WriteString(shellQuote(job.Dir))
+		builder.WriteString(" && ")
+	}
+	if lineToStdin {
+		if lineOptions.NullDelimited {
+			builder.WriteString("printf '%s\\0'")
+		} else {
+			builder.WriteString("printf '%s\\n'")
+		}
+		for _, input := range inputs {
+			builder.WriteByte(' ')
+			builder.WriteString(shellQuote(input))
+		}
+		builder.WriteString(" | ")
+	}
+	for _, env := range job.Env {
+		name, value, _ := strings.Cut(env, "=")
+		builder.WriteString(name)
+		builder.WriteByte('=')
+		builder.WriteString(shellQuote(value))
+		builder.WriteByte(' ')
+	}
+	writeShellCommand(&builder, cmdName, cmdArgs)
+	return builder.String(), nil
+}
+
+func writeShellCommand(builder *strings.Builder, cmdName string, cmdArgs []string) {
+	builder.WriteString(shellQuote(cmdName))
+	for _, arg := range cmdArgs {
+		builder.WriteByte(' ')
+		builder.WriteString(shellQuote(arg))
+	}
+}
+
+// POSIX shell quoting, only when needed
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, safeShellChars) == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -19,48 +19,85 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/dvaumoron/shelltools/pkg/cmdproxy"
 	"github.com/dvaumoron/shelltools/pkg/common"
 )
 
+// same as shells for a command ended by SIGINT
+const interruptedExitCode = 130
+
+var errInterrupted = errors.New("interrupted")
+
 const (
-	errorMessage = `Error: %[1]s
-Usage:
-  cmdforeach [CMD] [ARG ...] FILE [flags]
+	usageMessage = `Usage:
+  cmdforeach [flags] CMD [ARG ...] FILE
 
 Flags:
-  -h, --help   help for cmdforeach
-
-%[1]s
-`
+  -0, --null                  lines are delimited by NUL character in input and dry-run output (like find -print0)
+      --backoff DURATION      delay before first retry, doubled for each following one (default 1s)
+  -n, --batch N               pass up to N lines to each CMD call
+  -c, --capture               display result of each CMD call as a JSON object instead of its output
+      --cd DIR                run CMD in DIR ({} is replaced by the line)
+  -d, --dry-run               display shell quoted CMD calls instead of running them
+  -e, --env NAME=VALUE        set an environment variable for CMD ({} is replaced by the line, can be repeated)
+  -h, --help                  help for cmdforeach
+  -i, --interactive           ask confirmation before each CMD call (y/n/a for all/q for quit)
+  -j, --json                  decode each line as JSON, ARG can contain templates like {{ lower(name) }}
+      --kill-after DURATION   delay between SIGTERM and SIGKILL when timeout is reached (default 5s)
+  -s, --max-chars N           limit the size of each CMD call to N characters (default to system limit when batching)
+      --max-output N          truncate captured stdout and stderr to N bytes
+      --no-trim               keep leading and trailing white spaces of lines
+  -r, --retries N             retry each failed CMD call up to N times
+      --retry-on CODES        only retry on these exit codes (comma separated, 124 for timeout)
+      --stdin                 write the line to CMD standard input instead of appending it to ARG
+  -t, --timeout DURATION      stop each CMD call running longer than DURATION (like 30s)`
+
+	errorMessage = "Error: %[1]s\n" + usageMessage + "\n\n%[1]s\n"
 
 	helpMessage = `cmdforeach run one CMD augmented with each line from FILE,
-if FILE is -, read from standard input
-
-Usage:
-  cmdforeach [CMD] [ARG ...] FILE [flags]
-
-Flags:
-  -h, --help   help for cmdforeach`
+if FILE is -, read from standard input,
+lines are processed as soon as they are read, SIGINT and SIGTERM are forwarded to running CMD
+and its child processes (no new call is started after them),
+when FILE is read from standard input, CMD standard input is empty (unless stdin flag is used),
+flags must be placed before CMD,
+with json flag, each line is decoded and ARG templates are evaluated on it
+instead of appending the line (to know which expression is accepted in templates :
+see https://expr-lang.org/docs/language-definition),
+with batch or max-chars flag, several lines are appended to one CMD call
+(in json mode, leading ARG without template are passed once and the others are repeated for each line),
+with capture flag, CMD failures do not stop the processing, each call is displayed as a JSON object
+with input, argv, exitCode, duration (in seconds), attempts, timedOut, stdout and stderr fields
+
+` + usageMessage
 )
 
 func main() {
 	if err := cmdForEachWithInit(os.Args[1:]); err != nil {
+		if errors.Is(err, errInterrupted) {
+			os.Exit(interruptedExitCode)
+		}
 		fmt.Printf(errorMessage, err)
 		os.Exit(1)
 	}
 }
 
 func cmdForEachWithInit(args []string) error {
-	for _, arg := range args {
-		if arg == "-h" || arg == "--help" {
-			fmt.Println(helpMessage)
+	args, err := parseOptions(args)
+	if err != nil {
+		return err
+	}
 
-			return nil
-		}
+	if displayHelp {
+		fmt.Println(helpMessage)
+
+		return nil
 	}
 
 	argLen := len(args)
@@ -75,22 +112,106 @@ func cmdForEachWithInit(args []string) error {
 	}
 	defer closer()
 
-	return cmdForEach(args[0], args[1:last], src)
+	if err = initJob(src == os.Stdin); err != nil {
+		return err
+	}
+
+	prefix, itemOf := args[1:last], lineItem
+	switch {
+	case jsonInput:
+		if prefix, itemOf, err = initJsonItems(prefix); err != nil {
+			return err
+		}
+	case lineToStdin:
+		itemOf = noItem
+	}
+	return cmdForEach(args[0], prefix, itemOf, src)
+}
+
+func lineItem(line string) ([]string, error) {
+	return []string{line}, nil
 }
 
-func cmdForEach(cmdName string, cmdArgs []string, src *os.File) error {
-	lines, err := common.TrimmedLines(src)
-	if err != nil {
-		return err
+func noItem(line string) ([]string, error) {
+	return nil, nil
+}
+
+func cmdForEach(cmdName string, prefix []string, itemOf func(string) ([]string, error), src *os.File) error {
+	stopForward := cmdproxy.ForwardSignals(os.Interrupt, syscall.SIGTERM)
+	defer stopForward()
+
+	run := runCommand
+	switch {
+	case dryRun:
+		run = dryRunCommand
+	case captureOutput:
+		run = captureCommand
 	}
 
-	last := len(cmdArgs)
-	cmdArgs = append(cmdArgs, "")
-	for _, arg := range lines {
-		cmdArgs[last] = arg
+	if interactive && !dryRun {
+		tty, err := common.OpenTerminal()
+		if err != nil {
+			return err
+		}
+		defer tty.Close()
 
-		cmdproxy.Run(cmdName, cmdArgs)
+		run = (&confirmer{reader: bufio.NewReader(tty), run: run}).confirmCommand
 	}
 
-	return nil
+	var readErr error
+	var readCount atomic.Int64
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		scanner := lineOptions.NewScanner(src)
+		for scanner.Scan() {
+			readCount.Add(1)
+			lines <- lineOptions.Text(scanner)
+		}
+		readErr = scanner.Err()
+	}()
+
+	batch := newBatcher(run, cmdName, prefix)
+	for !cmdproxy.Stopped() {
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+		case <-cmdproxy.Done():
+		}
+		if !ok {
+			break
+		}
+
+		item, err := itemOf(line)
+		if err != nil {
+			return err
+		}
+
+		if err = batch.add(line, item); err != nil {
+			return ignoreQuit(err)
+		}
+	}
+
+	if !cmdproxy.Stopped() {
+		if readErr != nil {
+			return readErr
+		}
+		// the last call can be interrupted too
+		if err := batch.flush(); !cmdproxy.Stopped() {
+			return ignoreQuit(err)
+		}
+	}
+
+	pending := readCount.Load() - int64(batch.done)
+	fmt.Fprintf(os.Stderr, "Interrupted : %d line(s) processed, %d line(s) read but not processed\n", batch.done, pending)
+	return errInterrupted
+}
+
+func ignoreQuit(err error) error {
+	if errors.Is(err, errQuit) {
+		return nil
+	}
+	return err
 }
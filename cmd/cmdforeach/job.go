@@ -0,0 +1,121 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dvaumoron/shelltools/pkg/cmdproxy"
+)
+
+var (
+	errEnvFormat = errors.New("env flag value must be in NAME=VALUE format")
+	errJobBatch  = errors.New("env and cd flags can not be used with batch or max-chars flags")
+)
+
+var (
+	envTemplates []argTemplate
+	dirTemplate  *argTemplate
+	jobStdin     io.Reader
+)
+
+// env and cd values can use linePlaceholder (and expression templates in json mode)
+func initJob(inputFromStdin bool) error {
+	parse := func(value string) (argTemplate, error) {
+		if jsonInput {
+			return parseTemplate(value)
+		}
+		return literalTemplate(value), nil
+	}
+
+	for _, env := range envOptions {
+		if name, _, ok := strings.Cut(env, "="); !ok || name == "" {
+			return errEnvFormat
+		}
+
+		template, err := parse(env)
+		if err != nil {
+			return err
+		}
+		template.placeholder = true
+		envTemplates = append(envTemplates, template)
+	}
+
+	if dirOption != "" {
+		template, err := parse(dirOption)
+		if err != nil {
+			return err
+		}
+		template.placeholder = true
+		dirTemplate = &template
+	}
+
+	if (len(envTemplates) != 0 || dirTemplate != nil) && (batchSize > 1 || maxCharsOption != 0) {
+		return errJobBatch
+	}
+
+	// CMD must not consume the input
+	if inputFromStdin && !lineToStdin {
+		devNull, err := os.Open(os.DevNull)
+		if err != nil {
+			return err
+		}
+		jobStdin = devNull
+	}
+	return nil
+}
+
+func jobRunner(inputs []string) (cmdproxy.Runner, error) {
+	res := runner
+	res.Stdin = jobStdin
+	if lineToStdin {
+		delimiter := lineOptions.Delimiter()
+		res.Stdin = strings.NewReader(strings.Join(inputs, delimiter) + delimiter)
+	}
+
+	if len(envTemplates) == 0 && dirTemplate == nil {
+		return res, nil
+	}
+
+	// no batch here, so there is exactly one input
+	line := inputs[0]
+	var env any
+	if jsonInput {
+		var err error
+		if env, err = lineEnv(line); err != nil {
+			return cmdproxy.Runner{}, err
+		}
+	}
+
+	envs, err := expandAll(envTemplates, env, line)
+	if err != nil {
+		return cmdproxy.Runner{}, err
+	}
+	res.Env = envs
+
+	if dirTemplate != nil {
+		if res.Dir, err = dirTemplate.expand(env, line); err != nil {
+			return cmdproxy.Runner{}, err
+		}
+	}
+	return res, nil
+}
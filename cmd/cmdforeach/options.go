@@ -0,0 +1,170 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dvaumoron/shelltools/pkg/cmdproxy"
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+var (
+	displayHelp    bool
+	jsonInput      bool
+	batchSize      int
+	maxCharsOption int
+	captureOutput  bool
+	maxOutput      int
+	dryRun         bool
+	interactive    bool
+	envOptions     []string
+	dirOption      string
+	lineToStdin    bool
+	lineOptions    common.LineOptions
+	runner         = cmdproxy.Runner{KillDelay: 5 * time.Second, Backoff: time.Second}
+)
+
+// options are only accepted before CMD, in order to not mix with CMD own flags
+func parseOptions(args []string) ([]string, error) {
+	for len(args) != 0 {
+		arg := args[0]
+		if arg == "--" {
+			return args[1:], nil
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return args, nil
+		}
+
+		var err error
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "-h", "--help":
+			displayHelp, err = true, noValue(name, hasValue)
+		case "-0", "--null":
+			lineOptions.NullDelimited, err = true, noValue(name, hasValue)
+		case "--no-trim":
+			lineOptions.NoTrim, err = true, noValue(name, hasValue)
+		case "-j", "--json":
+			jsonInput, err = true, noValue(name, hasValue)
+		case "-d", "--dry-run":
+			dryRun, err = true, noValue(name, hasValue)
+		case "-i", "--interactive":
+			interactive, err = true, noValue(name, hasValue)
+		case "-e", "--env":
+			var env string
+			env, args, err = optionValue(name, value, hasValue, args)
+			envOptions = append(envOptions, env)
+		case "--cd":
+			dirOption, args, err = optionValue(name, value, hasValue, args)
+		case "--stdin":
+			lineToStdin, err = true, noValue(name, hasValue)
+		case "-c", "--capture":
+			captureOutput, err = true, noValue(name, hasValue)
+		case "--max-output":
+			maxOutput, args, err = positiveIntOption(name, value, hasValue, args)
+		case "-t", "--timeout":
+			runner.Timeout, args, err = durationOption(name, value, hasValue, args)
+		case "--kill-after":
+			runner.KillDelay, args, err = durationOption(name, value, hasValue, args)
+		case "-r", "--retries":
+			runner.Retries, args, err = positiveIntOption(name, value, hasValue, args)
+		case "--retry-on":
+			runner.RetryOn, args, err = intListOption(name, value, hasValue, args)
+		case "--backoff":
+			runner.Backoff, args, err = durationOption(name, value, hasValue, args)
+		case "-n", "--batch":
+			batchSize, args, err = positiveIntOption(name, value, hasValue, args)
+		case "-s", "--max-chars":
+			maxCharsOption, args, err = positiveIntOption(name, value, hasValue, args)
+		default:
+			err = fmt.Errorf("unknown flag: %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		args = args[1:]
+	}
+	return args, nil
+}
+
+func noValue(name string, hasValue bool) error {
+	if hasValue {
+		return fmt.Errorf("flag does not accept a value: %s", name)
+	}
+	return nil
+}
+
+// value is given with = or in next argument (then consumed)
+func optionValue(name string, value string, hasValue bool, args []string) (string, []string, error) {
+	if hasValue {
+		return value, args, nil
+	}
+	if len(args) < 2 {
+		return "", nil, fmt.Errorf("flag needs an argument: %s", name)
+	}
+	return args[1], args[1:], nil
+}
+
+func positiveIntOption(name string, value string, hasValue bool, args []string) (int, []string, error) {
+	value, args, err := optionValue(name, value, hasValue, args)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := strconv.Atoi(value)
+	if err != nil || res <= 0 {
+		return 0, nil, fmt.Errorf("invalid argument %q for %s flag: must be a positive integer", value, name)
+	}
+	return res, args, nil
+}
+
+func durationOption(name string, value string, hasValue bool, args []string) (time.Duration, []string, error) {
+	value, args, err := optionValue(name, value, hasValue, args)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := time.ParseDuration(value)
+	if err != nil || res <= 0 {
+		return 0, nil, fmt.Errorf("invalid argument %q for %s flag: must be a positive duration (like 30s)", value, name)
+	}
+	return res, args, nil
+}
+
+// comma separated list
+func intListOption(name string, value string, hasValue bool, args []string) ([]int, []string, error) {
+	value, args, err := optionValue(name, value, hasValue, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res []int
+	for _, part := range strings.Split(value, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid argument %q for %s flag: must be a list of integers", value, name)
+		}
+		res = append(res, code)
+	}
+	return res, args, nil
+}
@@ -0,0 +1,84 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/dvaumoron/shelltools/pkg/cmdproxy"
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+func runCommand(cmdName string, cmdArgs []string, inputs []string) error {
+	job, err := jobRunner(inputs)
+	if err != nil {
+		return err
+	}
+
+	job.Run(cmdName, cmdArgs)
+	return nil
+}
+
+// display one JSON object per call, with input (an array when batching), argv,
+// exitCode, duration (in seconds, of last attempt), attempts, timedOut, stdout and stderr
+func captureCommand(cmdName string, cmdArgs []string, inputs []string) error {
+	job, err := jobRunner(inputs)
+	if err != nil {
+		return err
+	}
+
+	result, err := job.Capture(cmdName, cmdArgs)
+	if errors.Is(err, cmdproxy.ErrStopped) {
+		return nil
+	}
+
+	jsonObject := common.NewObject(11)
+	if len(inputs) == 1 && batchSize <= 1 && maxCharsOption == 0 {
+		jsonObject.Set("input", inputs[0])
+	} else {
+		jsonObject.Set("input", inputs)
+	}
+	jsonObject.Set("argv", append([]string{cmdName}, cmdArgs...))
+	jsonObject.Set("exitCode", result.ExitCode)
+	jsonObject.Set("duration", result.Duration.Seconds())
+	jsonObject.Set("attempts", result.Attempts)
+	jsonObject.Set("timedOut", result.TimedOut)
+
+	stdout, stdoutTruncated := truncateOutput(result.Stdout)
+	stderr, stderrTruncated := truncateOutput(result.Stderr)
+	jsonObject.Set("stdout", stdout)
+	jsonObject.Set("stderr", stderr)
+	if maxOutput != 0 {
+		jsonObject.Set("truncated", stdoutTruncated || stderrTruncated)
+	}
+	if err != nil {
+		jsonObject.Set("error", err.Error())
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(jsonObject)
+}
+
+func truncateOutput(output []byte) (string, bool) {
+	if maxOutput == 0 || len(output) <= maxOutput {
+		return string(output), false
+	}
+	return string(output[:maxOutput]), true
+}
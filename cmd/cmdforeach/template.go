@@ -0,0 +1,163 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+const (
+	templateStart   = "{{"
+	templateEnd     = "}}"
+	linePlaceholder = "{}"
+)
+
+var errUnclosedTemplate = errors.New("template not closed, missing " + templateEnd)
+
+// an argument mixing literal parts and expressions (each prog is preceded by the literal at the same index),
+// when placeholder is set, linePlaceholder is replaced by the line in literal parts
+type argTemplate struct {
+	literals    []string
+	progs       []*vm.Program
+	placeholder bool
+}
+
+func literalTemplate(arg string) argTemplate {
+	return argTemplate{literals: []string{arg}}
+}
+
+func parseTemplate(arg string) (argTemplate, error) {
+	var template argTemplate
+	for {
+		start := strings.Index(arg, templateStart)
+		if start == -1 {
+			template.literals = append(template.literals, arg)
+			return template, nil
+		}
+
+		end := strings.Index(arg[start:], templateEnd)
+		if end == -1 {
+			return argTemplate{}, errUnclosedTemplate
+		}
+		end += start
+
+		prog, err := common.CompileExpr(strings.TrimSpace(arg[start+len(templateStart) : end]))
+		if err != nil {
+			return argTemplate{}, err
+		}
+
+		template.literals = append(template.literals, arg[:start])
+		template.progs = append(template.progs, prog)
+		arg = arg[end+len(templateEnd):]
+	}
+}
+
+func parseTemplates(args []string) ([]argTemplate, error) {
+	templates := make([]argTemplate, 0, len(args))
+	for _, arg := range args {
+		template, err := parseTemplate(arg)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+func (t argTemplate) expand(env any, line string) (string, error) {
+	if len(t.progs) == 0 {
+		return t.literal(0, line), nil
+	}
+
+	var builder strings.Builder
+	for index, prog := range t.progs {
+		builder.WriteString(t.literal(index, line))
+
+		value, err := expr.Run(prog, env)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(common.ToString(value, ""))
+	}
+	builder.WriteString(t.literal(len(t.progs), line))
+	return builder.String(), nil
+}
+
+func (t argTemplate) literal(index int, line string) string {
+	if t.placeholder {
+		return strings.ReplaceAll(t.literals[index], linePlaceholder, line)
+	}
+	return t.literals[index]
+}
+
+// leading arguments without expression are passed once per call (useful with batch),
+// the others are expanded for each line
+func initJsonItems(args []string) ([]string, func(string) ([]string, error), error) {
+	templates, err := parseTemplates(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := slices.IndexFunc(templates, func(template argTemplate) bool {
+		return len(template.progs) != 0
+	})
+	if index == -1 {
+		index = len(templates)
+	}
+
+	templates = templates[index:]
+	return args[:index], func(line string) ([]string, error) {
+		return expandTemplates(templates, line)
+	}, nil
+}
+
+func expandTemplates(templates []argTemplate, line string) ([]string, error) {
+	env, err := lineEnv(line)
+	if err != nil {
+		return nil, err
+	}
+	return expandAll(templates, env, line)
+}
+
+func lineEnv(line string) (any, error) {
+	jsonValue, err := common.Decode([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	return common.ToExprValue(jsonValue, nil), nil
+}
+
+func expandAll(templates []argTemplate, env any, line string) ([]string, error) {
+	values := make([]string, 0, len(templates))
+	for _, template := range templates {
+		value, err := template.expand(env, line)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
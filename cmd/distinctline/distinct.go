@@ -19,25 +19,93 @@
 package main
 
 import (
-	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/expr-lang/expr"
 	"github.com/spf13/cobra"
 
 	"github.com/dvaumoron/shelltools/pkg/common"
 )
 
+type valueCount struct {
+	value string
+	count int
+}
+
+var (
+	errKeepMode      = errors.New("keep mode must be first or last")
+	errBoundedMemory = errors.New("window and bloom flags can not be used with flags requiring to read all input (count, json, only-duplicates, only-unique, sort-count or keep last)")
+	errWindowSize    = errors.New("window size must be positive")
+	errFalsePositive = errors.New("false positive rate must be between 0 and 1 (excluded)")
+
+	keyColumns     []string
+	keyExpression  string
+	keepMode       string
+	displayCount   bool
+	jsonOutput     bool
+	onlyDuplicates bool
+	onlyUnique     bool
+	sortByCount    bool
+	hashValues     bool
+	lineOptions    = common.LineOptions{NoTrim: true}
+	windowSize     int
+	useBloom       bool
+	bloomCapacity  int
+	falsePositive  float64
+	unicodeForm    string
+	foldCase       bool
+	masks          []string
+	squeeze        bool
+	trim           bool
+)
+
 func main() {
 	cmd := cobra.Command{
 		Use:   "distinctline [FILE]",
 		Short: "distinctline echo input without repeated values.",
 		Long: `distinctline echo input without repeated values,
-without FILE or if FILE is -, read from standard input`,
+without FILE or if FILE is -, read from standard input,
+input does not need to be sorted (values are displayed in first seen order),
+with key or expr flag, lines are JSON objects compared on the computed key,
+to know which EXPRESSION is accepted : see https://expr-lang.org/docs/language-definition,
+with null flag, input and text output are delimited by NUL character (like find -print0),
+normalization flags only change the comparison, the first seen line is displayed unchanged,
+mask flag accepts a regular expression or one of the predefined masks : number, uuid, timestamp and hex`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: distinctLineWithInit,
 	}
 
+	cmdFlags := cmd.Flags()
+	cmdFlags.BoolVarP(&lineOptions.NullDelimited, "null", "0", false, "lines are delimited by NUL character in input and text output")
+	cmdFlags.StringSliceVarP(&keyColumns, "key", "k", nil, "compare JSON objects on these fields (comma separated, can be path like 'a.b[0].c')")
+	cmdFlags.StringVarP(&keyExpression, "expr", "e", "", "compare JSON objects on the result of EXPRESSION")
+	cmdFlags.StringVar(&keepMode, "keep", "first", "line to display for each distinct value (first or last)")
+	cmdFlags.BoolVarP(&displayCount, "count", "c", false, "prefix values by their number of occurrences")
+	cmdFlags.BoolVarP(&jsonOutput, "json", "j", false, "display values and their number of occurrences as JSON object")
+	cmdFlags.BoolVarP(&onlyDuplicates, "only-duplicates", "d", false, "only display repeated values")
+	cmdFlags.BoolVarP(&onlyUnique, "only-unique", "u", false, "only display values which are not repeated")
+	cmdFlags.BoolVarP(&sortByCount, "sort-count", "f", false, "sort values by decreasing number of occurrences")
+	cmdFlags.BoolVarP(&hashValues, "hash", "H", false, "store a 128 bits hash instead of each distinct value")
+	cmdFlags.IntVarP(&windowSize, "window", "w", 0, "only remember the N most recently seen values")
+	cmdFlags.BoolVarP(&useBloom, "bloom", "b", false, "use an approximate filter (some distinct values may be dropped)")
+	cmdFlags.IntVar(&bloomCapacity, "bloom-capacity", 1000000, "expected number of distinct values for bloom filter sizing")
+	cmdFlags.Float64Var(&falsePositive, "false-positive", 0.001, "false positive rate targeted by bloom filter")
+	cmdFlags.StringVar(&unicodeForm, "unicode", "", "apply a unicode normalization form before comparison (nfc, nfd, nfkc or nfkd)")
+	cmdFlags.BoolVarP(&foldCase, "ignore-case", "i", false, "ignore case in comparison")
+	cmdFlags.StringArrayVarP(&masks, "mask", "m", nil, "replace matches of regular expression (or predefined mask) before comparison (can be repeated)")
+	cmdFlags.BoolVarP(&squeeze, "squeeze-spaces", "s", false, "collapse successive white spaces before comparison")
+	cmdFlags.BoolVarP(&trim, "trim", "t", false, "ignore leading and trailing white spaces in comparison")
+	cmd.MarkFlagsMutuallyExclusive("only-duplicates", "only-unique")
+	cmd.MarkFlagsMutuallyExclusive("key", "expr")
+	cmd.MarkFlagsMutuallyExclusive("window", "bloom")
+
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -45,33 +113,262 @@ without FILE or if FILE is -, read from standard input`,
 }
 
 func distinctLineWithInit(cmd *cobra.Command, args []string) error {
+	if keepMode != "first" && keepMode != "last" {
+		return errKeepMode
+	}
+
+	keyOf, err := initKeyExtractor()
+	if err != nil {
+		return err
+	}
+	normalizer, err := initNormalizer(unicodeForm, foldCase, masks, squeeze, trim)
+	if err != nil {
+		return err
+	}
+	if normalizer != nil {
+		keyOf = normalizeKeys(keyOf, normalizer)
+	}
+	if hashValues {
+		keyOf = hashKeys(keyOf)
+	}
+
+	var seen seenSet = exactSet{}
+	bounded := cmd.Flags().Changed("window") || useBloom
+	switch {
+	case cmd.Flags().Changed("window"):
+		if windowSize <= 0 {
+			return errWindowSize
+		}
+		seen = newWindowSet(windowSize)
+	case useBloom:
+		if falsePositive <= 0 || falsePositive >= 1 {
+			return errFalsePositive
+		}
+		seen = newBloomFilter(bloomCapacity, falsePositive)
+	}
+
+	keepLast := keepMode == "last"
+	readAll := keepLast || displayCount || jsonOutput || onlyDuplicates || onlyUnique || sortByCount
+	if readAll && bounded {
+		return errBoundedMemory
+	}
+
 	src, closer, err := common.GetSource(args, 0)
 	if err != nil {
 		return err
 	}
 	defer closer()
 
-	return distinctLine(src)
+	if !readAll {
+		return distinctLine(src, keyOf, seen)
+	}
+
+	filter := func(count int) bool { return true }
+	switch {
+	case onlyDuplicates:
+		filter = func(count int) bool { return count > 1 }
+	case onlyUnique:
+		filter = func(count int) bool { return count == 1 }
+	}
+
+	writer := writeValue
+	switch {
+	case jsonOutput:
+		writer = writeJsonCount
+	case displayCount:
+		writer = writeCount
+	}
+	return countLine(src, keyOf, keepLast, filter, writer)
 }
 
-func distinctLine(src *os.File) error {
-	endLine := []byte{'\n'}
-	values := map[string]struct{}{}
-	scanner := bufio.NewScanner(src)
+func initKeyExtractor() (func(string) (string, error), error) {
+	switch {
+	case len(keyColumns) != 0:
+		common.TrimSlice(keyColumns)
+		paths := common.ParsePaths(keyColumns)
+
+		return func(line string) (string, error) {
+			jsonObject, err := common.DecodeObject([]byte(line))
+			if err != nil {
+				return "", err
+			}
+
+			values := make([]any, 0, len(paths))
+			for _, path := range paths {
+				value, _ := path.Extract(jsonObject)
+				values = append(values, value)
+			}
+			return jsonKey(values)
+		}, nil
+	case keyExpression != "":
+		prog, err := common.CompileExpr(keyExpression)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(line string) (string, error) {
+			jsonValue, err := common.Decode([]byte(line))
+			if err != nil {
+				return "", err
+			}
+
+			value, err := expr.Run(prog, common.ToExprValue(jsonValue, nil))
+			if err != nil {
+				return "", err
+			}
+			return jsonKey(value)
+		}, nil
+	}
+	return func(line string) (string, error) {
+		return line, nil
+	}, nil
+}
+
+// canonical encoding : numbers are compared exactly whatever their writing (1, 1.0 or 1e0)
+// and object fields are sorted
+func jsonKey(value any) (string, error) {
+	var builder strings.Builder
+	err := writeKey(&builder, value)
+	return builder.String(), err
+}
+
+func writeKey(builder *strings.Builder, value any) error {
+	switch casted := value.(type) {
+	case json.Number, int, int64, uint64, float64, *big.Int:
+		if rat, ok := common.ToRat(casted); ok {
+			builder.WriteString(rat.RatString())
+			return nil
+		}
+	case *common.Object:
+		return writeKeyObject(builder, slices.Clone(casted.Names()), func(name string) any {
+			subValue, _ := casted.Get(name)
+			return subValue
+		})
+	case map[string]any:
+		names := make([]string, 0, len(casted))
+		for name := range casted {
+			names = append(names, name)
+		}
+		return writeKeyObject(builder, names, func(name string) any {
+			return casted[name]
+		})
+	case []any:
+		builder.WriteByte('[')
+		for index, subValue := range casted {
+			if index != 0 {
+				builder.WriteByte(',')
+			}
+			if err := writeKey(builder, subValue); err != nil {
+				return err
+			}
+		}
+		builder.WriteByte(']')
+		return nil
+	}
+
+	encoded, err := json.Marshal(value)
+	builder.Write(encoded)
+	return err
+}
+
+func writeKeyObject(builder *strings.Builder, names []string, get func(string) any) error {
+	slices.Sort(names)
+	builder.WriteByte('{')
+	for index, name := range names {
+		if index != 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(strconv.Quote(name))
+		builder.WriteByte(':')
+		if err := writeKey(builder, get(name)); err != nil {
+			return err
+		}
+	}
+	builder.WriteByte('}')
+	return nil
+}
+
+func distinctLine(src *os.File, keyOf func(string) (string, error), seen seenSet) error {
+	endLine := lineOptions.Delimiter()
+	scanner := lineOptions.NewScanner(src)
 	for scanner.Scan() {
-		value := scanner.Text()
-		if _, ok := values[value]; ok {
+		value := lineOptions.Text(scanner)
+		key, err := keyOf(value)
+		if err != nil {
+			return err
+		}
+		if !seen.add(key) {
 			continue
 		}
-		values[value] = struct{}{}
 
 		if _, err := os.Stdout.WriteString(value); err != nil {
 			return err
 		}
-		if _, err := os.Stdout.Write(endLine); err != nil {
+		if _, err := os.Stdout.WriteString(endLine); err != nil {
 			return err
 		}
 
 	}
 	return scanner.Err()
 }
+
+// need to read all input before display
+func countLine(src *os.File, keyOf func(string) (string, error), keepLast bool, filter func(int) bool, writer func(valueCount) error) error {
+	var valueCounts []*valueCount
+	indexes := map[string]int{}
+	scanner := lineOptions.NewScanner(src)
+	for scanner.Scan() {
+		value := lineOptions.Text(scanner)
+		key, err := keyOf(value)
+		if err != nil {
+			return err
+		}
+		if index, ok := indexes[key]; ok {
+			counted := valueCounts[index]
+			counted.count++
+			if keepLast {
+				counted.value = value
+			}
+			continue
+		}
+		indexes[key] = len(valueCounts)
+		valueCounts = append(valueCounts, &valueCount{value: value, count: 1})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if sortByCount {
+		slices.SortStableFunc(valueCounts, func(a *valueCount, b *valueCount) int {
+			return b.count - a.count
+		})
+	}
+
+	for _, counted := range valueCounts {
+		if !filter(counted.count) {
+			continue
+		}
+		if err := writer(*counted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeValue(counted valueCount) error {
+	_, err := fmt.Print(counted.value, lineOptions.Delimiter())
+	return err
+}
+
+// same format as uniq -c
+func writeCount(counted valueCount) error {
+	_, err := fmt.Printf("%7d %s%s", counted.count, counted.value, lineOptions.Delimiter())
+	return err
+}
+
+func writeJsonCount(counted valueCount) error {
+	jsonObject := common.NewObject(2)
+	jsonObject.Set("value", counted.value)
+	jsonObject.Set("count", counted.count)
+	return json.NewEncoder(os.Stdout).Encode(jsonObject)
+}
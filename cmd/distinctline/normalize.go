@@ -0,0 +1,118 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const maskReplacement = "*"
+
+var errUnicodeForm = errors.New("unicode normalization form must be nfc, nfd, nfkc or nfkd")
+
+var unicodeForms = map[string]norm.Form{
+	"nfc":  norm.NFC,
+	"nfd":  norm.NFD,
+	"nfkc": norm.NFKC,
+	"nfkd": norm.NFKD,
+}
+
+// predefined masks usable by name instead of a regular expression
+var namedMasks = map[string]string{
+	"number":    `[-+]?\d+(?:\.\d+)?`,
+	"uuid":      `(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`,
+	"timestamp": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"hex":       `(?i)\b(?:0x)?[0-9a-f]{8,}\b`,
+}
+
+func initNormalizer(unicodeForm string, foldCase bool, masks []string, squeeze bool, trim bool) (func(string) string, error) {
+	var steps []func(string) string
+	if unicodeForm != "" {
+		form, ok := unicodeForms[strings.ToLower(unicodeForm)]
+		if !ok {
+			return nil, errUnicodeForm
+		}
+		steps = append(steps, form.String)
+	}
+	if foldCase {
+		folder := cases.Fold()
+		steps = append(steps, func(s string) string {
+			return folder.String(s)
+		})
+	}
+	for _, mask := range masks {
+		if named, ok := namedMasks[mask]; ok {
+			mask = named
+		}
+		re, err := regexp.Compile(mask)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, func(s string) string {
+			return re.ReplaceAllLiteralString(s, maskReplacement)
+		})
+	}
+	if squeeze {
+		steps = append(steps, squeezeSpaces)
+	}
+	if trim {
+		steps = append(steps, strings.TrimSpace)
+	}
+
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	return func(s string) string {
+		for _, step := range steps {
+			s = step(s)
+		}
+		return s
+	}, nil
+}
+
+// replace each run of white spaces by a single space
+func squeezeSpaces(s string) string {
+	var builder strings.Builder
+	inSpaces := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !inSpaces {
+				builder.WriteByte(' ')
+			}
+			inSpaces = true
+			continue
+		}
+		inSpaces = false
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+func normalizeKeys(keyOf func(string) (string, error), normalizer func(string) string) func(string) (string, error) {
+	return func(line string) (string, error) {
+		key, err := keyOf(line)
+		return normalizer(key), err
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"container/list"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+type seenSet interface {
+	// return true when key was not already seen
+	add(key string) bool
+}
+
+type exactSet map[string]struct{}
+
+func (s exactSet) add(key string) bool {
+	if _, ok := s[key]; ok {
+		return false
+	}
+	s[key] = struct{}{}
+	return true
+}
+
+// remember only the size most recently seen keys
+type windowSet struct {
+	size     int
+	elements map[string]*list.Element
+	recents  *list.List
+}
+
+func newWindowSet(size int) windowSet {
+	return windowSet{size: size, elements: make(map[string]*list.Element, size), recents: list.New()}
+}
+
+func (s windowSet) add(key string) bool {
+	if element, ok := s.elements[key]; ok {
+		s.recents.MoveToFront(element)
+		return false
+	}
+
+	if s.recents.Len() >= s.size {
+		oldest := s.recents.Back()
+		s.recents.Remove(oldest)
+		delete(s.elements, oldest.Value.(string))
+	}
+	s.elements[key] = s.recents.PushFront(key)
+	return true
+}
+
+// approximate set, can wrongly consider a key as seen (with a false positive rate
+// respected until capacity keys have been added), but never forget one
+type bloomFilter struct {
+	bits      []uint64
+	bitCount  uint64
+	hashCount uint64
+}
+
+func newBloomFilter(capacity int, falsePositiveRate float64) bloomFilter {
+	n := float64(max(capacity, 1))
+	bitCount := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	bitCount = max(bitCount, 64)
+	hashCount := uint64(math.Round(float64(bitCount) / n * math.Ln2))
+	return bloomFilter{bits: make([]uint64, (bitCount+63)/64), bitCount: bitCount, hashCount: max(hashCount, 1)}
+}
+
+func (f bloomFilter) add(key string) bool {
+	// double hashing from the two halves of a 128 bits hash
+	sum := hash128(key)
+	h1, h2 := binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:])|1
+
+	added := false
+	for i := uint64(0); i < f.hashCount; i++ {
+		position := (h1 + i*h2) % f.bitCount
+		index, mask := position/64, uint64(1)<<(position%64)
+		if f.bits[index]&mask == 0 {
+			f.bits[index] |= mask
+			added = true
+		}
+	}
+	return added
+}
+
+func hash128(key string) []byte {
+	hasher := fnv.New128a()
+	hasher.Write([]byte(key))
+	return hasher.Sum(nil)
+}
+
+// replace key by its 128 bits hash, to reduce memory usage with long lines
+func hashKeys(keyOf func(string) (string, error)) func(string) (string, error) {
+	return func(line string) (string, error) {
+		key, err := keyOf(line)
+		if err != nil {
+			return "", err
+		}
+		return string(hash128(key)), nil
+	}
+}
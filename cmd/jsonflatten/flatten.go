@@ -0,0 +1,126 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+const (
+	indexMode = "index"
+	keepMode  = "keep"
+)
+
+var (
+	errArrayMode = errors.New("array mode must be index or keep")
+
+	arrayMode string
+	separator string
+)
+
+func main() {
+	cmd := cobra.Command{
+		Use:   "jsonflatten [FILE]",
+		Short: "jsonflatten convert nested JSON object from FILE in flat JSON object.",
+		Long: `jsonflatten convert nested JSON object from FILE in flat JSON object,
+without FILE or if FILE is -, read from standard input,
+nested field are named by joining path with separator (like 'a.b.c'),
+array are flattened with index in path (mode index) or kept as value (mode keep)`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: jsonFlattenWithInit,
+	}
+
+	cmdFlags := cmd.Flags()
+	cmdFlags.StringVarP(&separator, "separator", "s", ".", "separator between path segments")
+	cmdFlags.StringVarP(&arrayMode, "arrays", "a", indexMode, "array handling (index or keep)")
+
+	if err := cmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func jsonFlattenWithInit(cmd *cobra.Command, args []string) error {
+	if arrayMode != indexMode && arrayMode != keepMode {
+		return errArrayMode
+	}
+
+	src, closer, err := common.GetSource(args, 0)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	return jsonFlatten(src, arrayMode == indexMode)
+}
+
+func jsonFlatten(src *os.File, flattenArray bool) error {
+	scanner := bufio.NewScanner(src)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		jsonObject, err := common.DecodeObject(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+
+		flatObject := common.NewObject(jsonObject.Len())
+		for _, name := range jsonObject.Names() {
+			value, _ := jsonObject.Get(name)
+			flattenValue(flatObject, name, value, flattenArray)
+		}
+
+		if err := encoder.Encode(flatObject); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func flattenValue(flatObject *common.Object, prefix string, value any, flattenArray bool) {
+	switch casted := value.(type) {
+	case *common.Object:
+		if casted.Len() == 0 {
+			flatObject.Set(prefix, casted)
+			return
+		}
+		for _, name := range casted.Names() {
+			subValue, _ := casted.Get(name)
+			flattenValue(flatObject, prefix+separator+name, subValue, flattenArray)
+		}
+	case []any:
+		if !flattenArray || len(casted) == 0 {
+			flatObject.Set(prefix, casted)
+			return
+		}
+		for index, subValue := range casted {
+			flattenValue(flatObject, prefix+separator+strconv.Itoa(index), subValue, flattenArray)
+		}
+	default:
+		flatObject.Set(prefix, value)
+	}
+}
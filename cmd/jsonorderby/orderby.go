@@ -21,11 +21,10 @@ package main
 import (
 	"bufio"
 	"cmp"
-	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"slices"
-	"strconv"
 	"strings"
 
 	"github.com/hashicorp/go-version"
@@ -59,7 +58,8 @@ func main() {
 		Use:   "jsonorderby COLUMN [FILE]",
 		Short: "jsonorderby sort JSON object from FILE on COLUMN field.",
 		Long: `jsonorderby sort JSON object from FILE on COLUMN field,
-without FILE or if FILE is -, read from standard input`,
+without FILE or if FILE is -, read from standard input,
+COLUMN can be a path in nested object (like 'a.b[0].c' or "a.'b.c'")`,
 		Args: cobra.RangeArgs(1, 2),
 		RunE: jsonOrderByWithInit,
 	}
@@ -79,7 +79,7 @@ without FILE or if FILE is -, read from standard input`,
 }
 
 func jsonOrderByWithInit(cmd *cobra.Command, args []string) error {
-	column := args[0]
+	column := common.ParsePath(args[0])
 
 	src, closer, err := common.GetSource(args, 1)
 	if err != nil {
@@ -89,34 +89,32 @@ func jsonOrderByWithInit(cmd *cobra.Command, args []string) error {
 
 	switch {
 	case extractAsNumber:
-		return orderBy(src, func(jsonObject map[string]any) float64 {
-			return extractFloat(jsonObject, column)
-		}, cmp.Compare[float64])
+		return orderBy(src, func(jsonObject *common.Object) *big.Rat {
+			value, _ := column.Extract(jsonObject)
+			return common.NumberKey(value)
+		}, (*big.Rat).Cmp)
 	case extractAsVersion:
-		return orderBy(src,
-			func(jsonObject map[string]any) *version.Version {
-				return extractVersion(jsonObject, column)
-			},
-			func(v1 *version.Version, v2 *version.Version) int {
-				return v1.Compare(v2)
-			})
+		return orderBy(src, func(jsonObject *common.Object) *version.Version {
+			value, _ := column.Extract(jsonObject)
+			return common.VersionKey(value)
+		}, common.CompareVersion)
 	case ignoreCase:
-		return orderBy(src, func(jsonObject map[string]any) string {
-			return strings.ToLower(common.ExtractString(jsonObject, column))
+		return orderBy(src, func(jsonObject *common.Object) string {
+			return strings.ToLower(common.ExtractString(jsonObject, column, ""))
 		}, cmp.Compare[string])
 	}
-	return orderBy(src, func(jsonObject map[string]any) string {
-		return common.ExtractString(jsonObject, column)
+	return orderBy(src, func(jsonObject *common.Object) string {
+		return common.ExtractString(jsonObject, column, "")
 	}, cmp.Compare[string])
 }
 
-func orderBy[T any](src *os.File, extracter func(map[string]any) T, cmpFunc func(T, T) int) error {
+func orderBy[T any](src *os.File, extracter func(*common.Object) T, cmpFunc func(T, T) int) error {
 	var attrAndDatas []attrAndData[T]
 	scanner := bufio.NewScanner(src)
 	for scanner.Scan() {
 		b := scanner.Bytes()
-		var jsonObject map[string]any
-		if err := json.Unmarshal(b, &jsonObject); err != nil {
+		jsonObject, err := common.DecodeObject(b)
+		if err != nil {
 			return err
 		}
 		attrAndDatas = append(attrAndDatas, attrAndData[T]{attr: extracter(jsonObject), data: b})
@@ -146,24 +144,3 @@ func orderBy[T any](src *os.File, extracter func(map[string]any) T, cmpFunc func
 	}
 	return nil
 }
-
-func extractFloat(jsonObject map[string]any, column string) float64 {
-	value := jsonObject[column]
-	switch casted := value.(type) {
-	case bool:
-		if casted {
-			return 1
-		}
-	case float64:
-		return casted
-	case string:
-		parsed, _ := strconv.ParseFloat(casted, 64)
-		return parsed
-	}
-	return 0
-}
-
-func extractVersion(jsonObject map[string]any, column string) *version.Version {
-	v, _ := version.NewVersion(common.ExtractString(jsonObject, column))
-	return v
-}
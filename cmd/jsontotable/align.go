@@ -0,0 +1,124 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+type alignment int
+
+const (
+	alignLeft alignment = iota
+	alignRight
+	alignCenter
+)
+
+var (
+	errAlignFormat = errors.New("alignment must be written as 'column:left', 'column:right' or 'column:center'")
+
+	alignOverrides map[string]alignment
+
+	numberRegexp = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+func parseAligns(specs []string) (map[string]alignment, error) {
+	aligns := make(map[string]alignment, len(specs))
+	for _, spec := range specs {
+		i := strings.LastIndexByte(spec, ':')
+		if i == -1 {
+			return nil, errAlignFormat
+		}
+
+		switch strings.TrimSpace(spec[i+1:]) {
+		case "left":
+			aligns[strings.TrimSpace(spec[:i])] = alignLeft
+		case "right":
+			aligns[strings.TrimSpace(spec[:i])] = alignRight
+		case "center":
+			aligns[strings.TrimSpace(spec[:i])] = alignCenter
+		default:
+			return nil, errAlignFormat
+		}
+	}
+	return aligns, nil
+}
+
+// column with only numeric values (ignoring empty and placeholder ones) are right aligned, unless overridden
+func computeAligns(titles []string, dataTable [][]string) []alignment {
+	aligns := make([]alignment, len(titles))
+	for index, title := range titles {
+		if align, ok := alignOverrides[title]; ok {
+			aligns[index] = align
+		} else if isNumericColumn(index, dataTable) {
+			aligns[index] = alignRight
+		}
+	}
+	return aligns
+}
+
+func isNumericColumn(index int, dataTable [][]string) bool {
+	numeric := false
+	for _, line := range dataTable {
+		if index >= len(line) {
+			continue
+		}
+
+		switch value := line[index]; {
+		case value == "" || value == nullValue:
+		case numberRegexp.MatchString(value):
+			numeric = true
+		default:
+			return false
+		}
+	}
+	return numeric
+}
+
+// color code is an ANSI SGR parameter (no color when empty)
+func writeAligned(outputBuilder *strings.Builder, value string, size int, align alignment, code string) {
+	padding := size - len([]rune(value))
+	before := 0
+	switch align {
+	case alignRight:
+		before = padding
+	case alignCenter:
+		before = padding / 2
+	}
+
+	if code != "" {
+		outputBuilder.WriteString("\x1b[")
+		outputBuilder.WriteString(code)
+		outputBuilder.WriteByte('m')
+	}
+	writeSpaces(outputBuilder, before)
+	outputBuilder.WriteString(value)
+	writeSpaces(outputBuilder, padding-before)
+	if code != "" {
+		outputBuilder.WriteString(resetCode)
+	}
+}
+
+func writeSpaces(outputBuilder *strings.Builder, count int) {
+	for counter := 0; counter < count; counter++ {
+		outputBuilder.WriteByte(' ')
+	}
+}
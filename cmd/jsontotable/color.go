@@ -0,0 +1,168 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+const (
+	boldCode  = "1"
+	resetCode = "\x1b[0m"
+)
+
+type highlightRule struct {
+	pred   func(any) bool
+	column string // empty for whole line
+	code   string
+}
+
+var (
+	errColorMode       = errors.New("color mode must be auto, always or never")
+	errHighlightFormat = errors.New("highlight must be written as 'EXPRESSION:COLOR' or 'EXPRESSION:column=COLOR'")
+
+	colorCodes = map[string]string{
+		"bold": boldCode, "dim": "2", "italic": "3", "underline": "4", "reverse": "7",
+		"black": "30", "red": "31", "green": "32", "yellow": "33",
+		"blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	}
+
+	colorEnabled   bool
+	highlightRules []highlightRule
+)
+
+func initColorEnabled(mode string) error {
+	switch mode {
+	case "always":
+		colorEnabled = true
+	case "never":
+		colorEnabled = false
+	case "auto":
+		colorEnabled = os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+	default:
+		return errColorMode
+	}
+	return nil
+}
+
+func parseHighlights(specs []string) ([]highlightRule, error) {
+	rules := make([]highlightRule, 0, len(specs))
+	for _, spec := range specs {
+		i := strings.LastIndexByte(spec, ':')
+		if i == -1 {
+			return nil, errHighlightFormat
+		}
+
+		pred, err := common.ParsePredicate(spec[:i])
+		if err != nil {
+			return nil, err
+		}
+
+		column, colorName := "", strings.TrimSpace(spec[i+1:])
+		if j := strings.LastIndexByte(colorName, '='); j != -1 {
+			column, colorName = strings.TrimSpace(colorName[:j]), strings.TrimSpace(colorName[j+1:])
+		}
+
+		code, err := parseColor(colorName)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, highlightRule{pred: pred, column: column, code: code})
+	}
+	return rules, nil
+}
+
+// accept combination like 'bold+red'
+func parseColor(colorName string) (string, error) {
+	names := strings.Split(colorName, "+")
+	codes := make([]string, 0, len(names))
+	for _, name := range names {
+		code, ok := colorCodes[strings.TrimSpace(name)]
+		if !ok {
+			return "", fmt.Errorf("unknown color %q", name)
+		}
+		codes = append(codes, code)
+	}
+	return strings.Join(codes, ";"), nil
+}
+
+// last matching rule take precedence
+func appendColors(colors [][]string, titles []string, jsonObject *common.Object) [][]string {
+	if !colorEnabled || len(highlightRules) == 0 {
+		return colors
+	}
+
+	codes := make([]string, len(titles))
+	exprValue := common.ToExprValue(jsonObject, nil)
+	for _, rule := range highlightRules {
+		if !rule.pred(exprValue) {
+			continue
+		}
+
+		for index, title := range titles {
+			if rule.column == "" || rule.column == title {
+				codes[index] = rule.code
+			}
+		}
+	}
+	return append(colors, codes)
+}
+
+// add bold header, return nil when color is disabled
+func completeColors(colors [][]string, numColumns int, displayHeader bool, numLines int) [][]string {
+	if !colorEnabled {
+		return nil
+	}
+
+	completed := make([][]string, 0, numLines)
+	if displayHeader {
+		header := make([]string, numColumns)
+		for index := range header {
+			header[index] = boldCode
+		}
+		completed = append(completed, header)
+	}
+	completed = append(completed, colors...)
+	for len(completed) < numLines {
+		completed = append(completed, nil)
+	}
+	return completed
+}
+
+func cellColor(colors [][]string, lineIndex int, columnIndex int) string {
+	if lineIndex < len(colors) {
+		return lineColor(colors[lineIndex], columnIndex)
+	}
+	return ""
+}
+
+func lineColor(codes []string, columnIndex int) string {
+	if columnIndex < len(codes) {
+		return codes[columnIndex]
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+type column struct {
+	title string
+	path  common.Path
+	prog  *vm.Program // when not nil, used instead of path
+}
+
+// the expression value of object is computed once (when needed) and stored in exprValue
+func (c column) extract(jsonObject *common.Object, exprValue *any) (any, bool) {
+	if c.prog == nil {
+		return c.path.Extract(jsonObject)
+	}
+
+	if *exprValue == nil {
+		*exprValue = common.ToExprValue(jsonObject, nil)
+	}
+	value, err := expr.Run(c.prog, *exprValue)
+	return value, err == nil
+}
+
+func literalColumn(name string) column {
+	return column{title: name, path: common.LiteralPath(name)}
+}
+
+// accept path (like 'a.b[0].c') or title with expression (like 'Size (MB)=size/1e6')
+func parseColumns(specs []string) ([]column, error) {
+	columns := make([]column, 0, len(specs))
+	for _, spec := range specs {
+		if title, expression, ok := splitTitle(spec); ok {
+			prog, err := common.CompileExpr(expression)
+			if err != nil {
+				return nil, err
+			}
+
+			columns = append(columns, column{title: title, prog: prog})
+			continue
+		}
+
+		columns = append(columns, column{title: spec, path: common.ParsePath(spec)})
+	}
+	return columns, nil
+}
+
+// split on comma outside of quotes, parenthesis, brackets and braces
+func splitColumnSpecs(rawSpecs []string) []string {
+	var specs []string
+	for _, rawSpec := range rawSpecs {
+		specs = append(specs, splitColumnSpec(rawSpec)...)
+	}
+	return specs
+}
+
+// unbalanced quotes or brackets (like in "owner's") are taken literally
+func splitColumnSpec(rawSpec string) []string {
+	var specs []string
+	depth, start, escaped := 0, 0, false
+	var quote rune
+	for index, c := range rawSpec {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			switch c {
+			case '\\':
+				escaped = true
+			case quote:
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			specs = append(specs, rawSpec[start:index])
+			start = index + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return strings.Split(rawSpec, ",")
+	}
+	return append(specs, rawSpec[start:])
+}
+
+// split on first '=' which is not part of a comparison operator
+func splitTitle(spec string) (string, string, bool) {
+	i := strings.IndexByte(spec, '=')
+	if i < 1 || strings.IndexByte("!<>=", spec[i-1]) != -1 || (i+1 < len(spec) && spec[i+1] == '=') {
+		return "", "", false
+	}
+	return strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:]), true
+}
+
+func extractValues(columns []column, jsonObject *common.Object) []any {
+	var exprValue any
+	values := make([]any, len(columns))
+	for index, column := range columns {
+		values[index], _ = column.extract(jsonObject, &exprValue)
+	}
+	return values
+}
@@ -0,0 +1,175 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+var errFooterFormat = errors.New("footer must be written as 'column:aggregate' (aggregate is count, sum, avg, min or max)")
+
+type aggregator interface {
+	add(value any)
+	result() string
+}
+
+type countAggregator struct {
+	count int
+}
+
+func (c *countAggregator) add(value any) {
+	if value != nil {
+		c.count++
+	}
+}
+
+func (c *countAggregator) result() string {
+	return strconv.Itoa(c.count)
+}
+
+// sum and average of numeric values (others are ignored)
+type sumAggregator struct {
+	average bool
+	count   int64
+	sum     big.Rat
+}
+
+func (s *sumAggregator) add(value any) {
+	if number, ok := common.ToRat(value); ok {
+		s.count++
+		s.sum.Add(&s.sum, number)
+	}
+}
+
+func (s *sumAggregator) result() string {
+	if !s.average {
+		return formatRat(&s.sum)
+	}
+	if s.count == 0 {
+		return ""
+	}
+	return formatRat(new(big.Rat).Quo(&s.sum, big.NewRat(s.count, 1)))
+}
+
+type boundAggregator struct {
+	keepSign int // -1 to keep minimum, 1 to keep maximum
+	bound    *big.Rat
+}
+
+func (b *boundAggregator) add(value any) {
+	if number, ok := common.ToRat(value); ok && (b.bound == nil || number.Cmp(b.bound) == b.keepSign) {
+		b.bound = number
+	}
+}
+
+func (b *boundAggregator) result() string {
+	if b.bound == nil {
+		return ""
+	}
+	return formatRat(b.bound)
+}
+
+func parseFooters(specs []string) (map[string]func() aggregator, error) {
+	footers := make(map[string]func() aggregator, len(specs))
+	for _, spec := range specs {
+		i := strings.LastIndexByte(spec, ':')
+		if i == -1 {
+			return nil, errFooterFormat
+		}
+
+		var builder func() aggregator
+		switch aggregate := strings.TrimSpace(spec[i+1:]); aggregate {
+		case "count":
+			builder = func() aggregator { return &countAggregator{} }
+		case "sum":
+			builder = func() aggregator { return &sumAggregator{} }
+		case "avg":
+			builder = func() aggregator { return &sumAggregator{average: true} }
+		case "min":
+			builder = func() aggregator { return &boundAggregator{keepSign: -1} }
+		case "max":
+			builder = func() aggregator { return &boundAggregator{keepSign: 1} }
+		default:
+			return nil, fmt.Errorf("unknown aggregate %q", aggregate)
+		}
+		// one footer line, so one aggregate by column
+		name := strings.TrimSpace(spec[:i])
+		if _, ok := footers[name]; ok {
+			return nil, fmt.Errorf("several aggregates for column %q", name)
+		}
+		footers[name] = builder
+	}
+	return footers, nil
+}
+
+// return nil when there is no footer (aggregators are aligned with columns, nil for column without footer)
+func initAggregators(columns []column) []aggregator {
+	if len(footerBuilders) == 0 {
+		return nil
+	}
+
+	aggregators := make([]aggregator, len(columns))
+	for index, column := range columns {
+		if builder, ok := footerBuilders[column.title]; ok {
+			aggregators[index] = builder()
+		}
+	}
+	return aggregators
+}
+
+func aggregate(aggregators []aggregator, values []any) {
+	for index, aggregator := range aggregators {
+		if aggregator != nil {
+			aggregator.add(values[index])
+		}
+	}
+}
+
+// return nil when there is no footer
+func buildFooter(aggregators []aggregator, displayLineNum bool) []string {
+	if aggregators == nil {
+		return nil
+	}
+
+	footer := make([]string, 0, len(aggregators)+1)
+	if displayLineNum {
+		footer = append(footer, "")
+	}
+	for _, aggregator := range aggregators {
+		if aggregator == nil {
+			footer = append(footer, "")
+		} else {
+			footer = append(footer, aggregator.result())
+		}
+	}
+	return footer
+}
+
+func formatRat(number *big.Rat) string {
+	if number.IsInt() {
+		return number.RatString()
+	}
+	return strings.TrimRight(strings.TrimRight(number.FloatString(6), "0"), ".")
+}
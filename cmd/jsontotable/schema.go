@@ -0,0 +1,139 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+const maxSamples = 3
+
+type fieldStat struct {
+	column    column
+	present   int
+	nullCount int
+	types     []string
+	samples   []string
+}
+
+func (f *fieldStat) observe(value any) {
+	f.present++
+	if value == nil {
+		f.nullCount++
+	}
+
+	if typeName := jsonTypeName(value); !slices.Contains(f.types, typeName) {
+		f.types = append(f.types, typeName)
+	}
+
+	if value != nil && len(f.samples) < maxSamples {
+		if sample := common.ToString(value, ""); !slices.Contains(f.samples, sample) {
+			f.samples = append(f.samples, sample)
+		}
+	}
+}
+
+func (f *fieldStat) toLine(total int) []string {
+	nullRate := 0.0
+	if total != 0 {
+		nullRate = float64(f.nullCount+total-f.present) * 100 / float64(total)
+	}
+
+	return []string{
+		f.column.title,
+		strings.Join(f.types, ", "),
+		strconv.FormatFloat(nullRate, 'f', 1, 64) + "%",
+		strings.Join(f.samples, ", "),
+	}
+}
+
+// without columns, all attributes are reported (in first seen order)
+func jsonSchema(columns []column, src *os.File, builder tableBuilder) error {
+	stats := make([]*fieldStat, 0, len(columns))
+	for _, column := range columns {
+		stats = append(stats, &fieldStat{column: column})
+	}
+	statsByName := map[string]*fieldStat{}
+
+	total := 0
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		jsonObject, err := common.DecodeObject(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+		total++
+
+		if len(columns) == 0 {
+			for _, name := range jsonObject.Names() {
+				stat, ok := statsByName[name]
+				if !ok {
+					stat = &fieldStat{column: literalColumn(name)}
+					statsByName[name] = stat
+					stats = append(stats, stat)
+				}
+				value, _ := jsonObject.Get(name)
+				stat.observe(value)
+			}
+			continue
+		}
+
+		var exprValue any
+		for _, stat := range stats {
+			if value, ok := stat.column.extract(jsonObject, &exprValue); ok {
+				stat.observe(value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	table := [][]string{{"field", "types", "null rate", "samples"}}
+	for _, stat := range stats {
+		table = append(table, stat.toLine(total))
+	}
+	return displayTable(table[0], false, builder, table, nil, nil)
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, int, int64, uint64, float64, *big.Int:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case *common.Object, map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
@@ -0,0 +1,103 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type interlineStyle struct {
+	left  string
+	fill  string
+	cross string
+	right string
+}
+
+type frameStyle struct {
+	top    interlineStyle
+	middle interlineStyle
+	bottom interlineStyle
+	left   string
+	inner  string
+	right  string
+}
+
+var frameStyles = map[string]frameStyle{
+	"ascii": {
+		top:    interlineStyle{left: "+", fill: "-", cross: "+", right: "+"},
+		middle: interlineStyle{left: "+", fill: "-", cross: "+", right: "+"},
+		bottom: interlineStyle{left: "+", fill: "-", cross: "+", right: "+"},
+		left:   "|", inner: "|", right: "|",
+	},
+	"light": {
+		top:    interlineStyle{left: "┌", fill: "─", cross: "┬", right: "┐"},
+		middle: interlineStyle{left: "├", fill: "─", cross: "┼", right: "┤"},
+		bottom: interlineStyle{left: "└", fill: "─", cross: "┴", right: "┘"},
+		left:   "│", inner: "│", right: "│",
+	},
+	"heavy": {
+		top:    interlineStyle{left: "┏", fill: "━", cross: "┳", right: "┓"},
+		middle: interlineStyle{left: "┣", fill: "━", cross: "╋", right: "┫"},
+		bottom: interlineStyle{left: "┗", fill: "━", cross: "┻", right: "┛"},
+		left:   "┃", inner: "┃", right: "┃",
+	},
+	"double": {
+		top:    interlineStyle{left: "╔", fill: "═", cross: "╦", right: "╗"},
+		middle: interlineStyle{left: "╠", fill: "═", cross: "╬", right: "╣"},
+		bottom: interlineStyle{left: "╚", fill: "═", cross: "╩", right: "╝"},
+		left:   "║", inner: "║", right: "║",
+	},
+	"rounded": {
+		top:    interlineStyle{left: "╭", fill: "─", cross: "┬", right: "╮"},
+		middle: interlineStyle{left: "├", fill: "─", cross: "┼", right: "┤"},
+		bottom: interlineStyle{left: "╰", fill: "─", cross: "┴", right: "╯"},
+		left:   "│", inner: "│", right: "│",
+	},
+	"compact": { // no inner border
+		top:    interlineStyle{left: "+", fill: "-", cross: "-", right: "+"},
+		middle: interlineStyle{left: "+", fill: "-", cross: "-", right: "+"},
+		bottom: interlineStyle{left: "+", fill: "-", cross: "-", right: "+"},
+		left:   "|", inner: " ", right: "|",
+	},
+}
+
+func getFrameStyle(name string) (frameStyle, error) {
+	style, ok := frameStyles[name]
+	if !ok {
+		return frameStyle{}, fmt.Errorf("unknown style %q (available : ascii, light, heavy, double, rounded, compact)", name)
+	}
+	return style, nil
+}
+
+func buildInterline(style interlineStyle, maxColumnSizes []int) string {
+	var builder strings.Builder
+	builder.WriteString(style.left)
+	for index, maxColumnSize := range maxColumnSizes {
+		if index != 0 {
+			builder.WriteString(style.cross)
+		}
+		for counter := -2; counter < maxColumnSize; counter++ {
+			builder.WriteString(style.fill)
+		}
+	}
+	builder.WriteString(style.right)
+	builder.WriteByte('\n')
+	return builder.String()
+}
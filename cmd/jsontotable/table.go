@@ -20,10 +20,8 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
-	"slices"
 	"strconv"
 	"strings"
 
@@ -32,13 +30,34 @@ import (
 	"github.com/dvaumoron/shelltools/pkg/common"
 )
 
-type tableBuilder = func([]int, [][]string) string
+type tableBuilder = func(tableLayout, [][]string) string
+
+// computed once all lines are known
+type tableLayout struct {
+	titles         []string
+	hasHeader      bool // first line is a header
+	maxColumnSizes []int
+	aligns         []alignment
+	colors         [][]string // ANSI SGR parameter by cell, nil without color
+	hasFooter      bool       // last line is a footer
+}
 
 var (
 	columns        []string
 	simple         bool
 	skipHeader     bool
 	displayLineNum bool
+	nullValue      string
+	alignSpecs     []string
+	styleName      string
+	rowSeparator   bool
+	vertical       bool
+	colorMode      string
+	highlights     []string
+	footers        []string
+	footerBuilders map[string]func() aggregator
+	unionColumns   bool
+	schemaReport   bool
 )
 
 func main() {
@@ -47,16 +66,32 @@ func main() {
 		Short: "jsontotable display JSON object from FILE as a table.",
 		Long: `jsontotable display JSON object from FILE as a table,
 without FILE or if FILE is -, read from standard input,
-without columns flag, display all attribute in sorted order (based on first object)`,
+without columns flag, display all attribute in order of the first object
+(or in first seen order of all objects with all-columns flag),
+column name can be a path in nested object (like 'a.b[0].c' or "a.'b.c'")
+or a title with an expression (like 'Size (MB)=size/1e6'),
+to know which expression is accepted : see https://expr-lang.org/docs/language-definition`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: jsonToTableWithInit,
 	}
 
 	cmdFlags := cmd.Flags()
-	cmdFlags.StringSliceVarP(&columns, "columns", "c", nil, "name of the columns (comma separated)")
+	cmdFlags.StringArrayVarP(&columns, "columns", "c", nil, "name of the columns (comma separated), can be TITLE=EXPRESSION")
 	cmdFlags.BoolVarP(&simple, "simple", "s", false, "simplify display (no ascii frame)")
 	cmdFlags.BoolVarP(&skipHeader, "no-header", "n", false, "do not display header")
 	cmdFlags.BoolVarP(&displayLineNum, "display-line-number", "l", false, "display line number")
+	cmdFlags.StringVar(&nullValue, "null", "", "placeholder for missing or null value")
+	cmdFlags.StringVar(&styleName, "style", "ascii", "frame style (ascii, light, heavy, double, rounded or compact)")
+	cmdFlags.BoolVar(&rowSeparator, "row-separator", false, "display a separator between each line")
+	cmdFlags.BoolVarP(&vertical, "vertical", "x", false, "display each object as a block of attribute and value lines")
+	cmdFlags.BoolVarP(&interactive, "interactive", "i", false, "display in an interactive viewer (frozen header, scrolling, search and sort)")
+	cmdFlags.StringVar(&colorMode, "color", "auto", "color output (auto, always or never), auto disable it when output is not a terminal or NO_COLOR is set")
+	cmdFlags.StringArrayVar(&highlights, "highlight", nil, "color line or cell matching EXPRESSION (like 'size > 1000:red' or 'size > 1000:size=bold+red')")
+	cmdFlags.StringSliceVar(&footers, "footer", nil, "display a footer line with aggregates (like 'size:sum,name:count,latency:avg'), available aggregates are count, sum, avg, min and max")
+	cmdFlags.StringSliceVar(&alignSpecs, "align", nil, "alignment of columns (like 'size:right,name:center'), numeric columns are right aligned by default")
+	cmdFlags.BoolVarP(&unionColumns, "all-columns", "a", false, "display attributes from all objects (not only first one)")
+	cmdFlags.BoolVar(&schemaReport, "schema", false, "display observed types, null rate and sample values of each attribute")
+	cmd.MarkFlagsMutuallyExclusive("columns", "all-columns")
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -65,7 +100,32 @@ without columns flag, display all attribute in sorted order (based on first obje
 }
 
 func jsonToTableWithInit(cmd *cobra.Command, args []string) error {
+	columns = splitColumnSpecs(columns)
 	common.TrimSlice(columns)
+	parsedColumns, err := parseColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	if alignOverrides, err = parseAligns(alignSpecs); err != nil {
+		return err
+	}
+	style, err := getFrameStyle(styleName)
+	if err != nil {
+		return err
+	}
+	if err = initColorEnabled(colorMode); err != nil {
+		return err
+	}
+	if interactive {
+		colorEnabled = false // escape sequences would be broken by horizontal scrolling
+	}
+	if highlightRules, err = parseHighlights(highlights); err != nil {
+		return err
+	}
+	if footerBuilders, err = parseFooters(footers); err != nil {
+		return err
+	}
 
 	src, closer, err := common.GetSource(args, 0)
 	if err != nil {
@@ -74,50 +134,108 @@ func jsonToTableWithInit(cmd *cobra.Command, args []string) error {
 	defer closer()
 
 	var builder tableBuilder
-	if simple {
+	switch {
+	case vertical && simple:
+		builder = buildVerticalLines
+	case vertical:
+		builder = func(layout tableLayout, table [][]string) string {
+			return buildVerticalTable(style, layout, table)
+		}
+	case simple:
 		builder = buildLines
-	} else {
-		builder = func(maxColumnSizes []int, table [][]string) string {
-			return buildTable(!skipHeader, maxColumnSizes, table)
+	default:
+		builder = func(layout tableLayout, table [][]string) string {
+			return buildTable(style, rowSeparator, layout, table)
 		}
 	}
-	return jsonToTable(columns, src, skipHeader, displayLineNum, builder)
+	if schemaReport {
+		return jsonSchema(parsedColumns, src, builder)
+	}
+	if unionColumns {
+		return jsonToTableAllColumns(src, skipHeader, displayLineNum, nullValue, builder)
+	}
+	return jsonToTable(parsedColumns, src, skipHeader, displayLineNum, nullValue, builder)
 }
 
-func jsonToTable(columns []string, src *os.File, skipHeader bool, displayLineNum bool, builder tableBuilder) error {
+func jsonToTable(columns []column, src *os.File, skipHeader bool, displayLineNum bool, placeholder string, builder tableBuilder) error {
 	initLine := initBasicLine
 	if displayLineNum {
 		initLine = initLineWithIndex
 	}
 
-	lineSize := 0
-	var table [][]string
+	var titles []string
+	var table, colors [][]string
+	var aggregators []aggregator
 	scanner := bufio.NewScanner(src)
 	if scanner.Scan() {
-		var jsonObject map[string]any
-		if err := json.Unmarshal(scanner.Bytes(), &jsonObject); err != nil {
+		jsonObject, err := common.DecodeObject(scanner.Bytes())
+		if err != nil {
 			return err
 		}
 
 		if len(columns) == 0 {
 			columns = extractColumnNames(jsonObject, columns)
 		}
-		lineSize, table = initLineSizeAndTable(skipHeader, displayLineNum, columns)
+		titles, table = initTitlesAndTable(skipHeader, displayLineNum, columns)
+		aggregators = initAggregators(columns)
 
-		table = appendLine(table, initLine(lineSize, 0), columns, jsonObject)
+		values := extractValues(columns, jsonObject)
+		table = appendLine(table, initLine(len(titles), 0), values, placeholder)
+		colors = appendColors(colors, titles, jsonObject)
+		aggregate(aggregators, values)
 	}
 	for index := 1; scanner.Scan(); index++ {
-		var jsonObject map[string]any
-		if err := json.Unmarshal(scanner.Bytes(), &jsonObject); err != nil {
+		jsonObject, err := common.DecodeObject(scanner.Bytes())
+		if err != nil {
 			return err
 		}
 
-		table = appendLine(table, initLine(lineSize, index), columns, jsonObject)
+		values := extractValues(columns, jsonObject)
+		table = appendLine(table, initLine(len(titles), index), values, placeholder)
+		colors = appendColors(colors, titles, jsonObject)
+		aggregate(aggregators, values)
 	}
 	if err := scanner.Err(); err != nil {
 		return err
 	}
-	return displayTable(lineSize, builder, table)
+	return displayTable(titles, skipHeader, builder, table, colors, buildFooter(aggregators, displayLineNum))
+}
+
+func jsonToTableAllColumns(src *os.File, skipHeader bool, displayLineNum bool, placeholder string, builder tableBuilder) error {
+	jsonObjects, err := readObjects(src)
+	if err != nil {
+		return err
+	}
+
+	initLine := initBasicLine
+	if displayLineNum {
+		initLine = initLineWithIndex
+	}
+
+	columns := extractAllColumnNames(jsonObjects)
+	var colors [][]string
+	titles, table := initTitlesAndTable(skipHeader, displayLineNum, columns)
+	aggregators := initAggregators(columns)
+	for index, jsonObject := range jsonObjects {
+		values := extractValues(columns, jsonObject)
+		table = appendLine(table, initLine(len(titles), index), values, placeholder)
+		colors = appendColors(colors, titles, jsonObject)
+		aggregate(aggregators, values)
+	}
+	return displayTable(titles, skipHeader, builder, table, colors, buildFooter(aggregators, displayLineNum))
+}
+
+func readObjects(src *os.File) ([]*common.Object, error) {
+	var jsonObjects []*common.Object
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		jsonObject, err := common.DecodeObject(scanner.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		jsonObjects = append(jsonObjects, jsonObject)
+	}
+	return jsonObjects, scanner.Err()
 }
 
 func initBasicLine(lineSize int, index int) []string {
@@ -130,104 +248,134 @@ func initLineWithIndex(lineSize int, index int) []string {
 	return line
 }
 
-func extractColumnNames(jsonObject map[string]any, _ []string) []string {
-	names := make([]string, 0, len(jsonObject))
-	for name := range jsonObject {
-		names = append(names, name)
+func extractColumnNames(jsonObject *common.Object, _ []column) []column {
+	names := jsonObject.Names()
+	columns := make([]column, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, literalColumn(name))
 	}
-	slices.Sort(names)
-	return names
+	return columns
 }
 
-func initLineSizeAndTable(skipHeader bool, displayLineNum bool, columns []string) (int, [][]string) {
+// union of attribute names in first seen order
+func extractAllColumnNames(jsonObjects []*common.Object) []column {
+	var columns []column
+	seen := map[string]struct{}{}
+	for _, jsonObject := range jsonObjects {
+		for _, name := range jsonObject.Names() {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				columns = append(columns, literalColumn(name))
+			}
+		}
+	}
+	return columns
+}
+
+func initTitlesAndTable(skipHeader bool, displayLineNum bool, columns []column) ([]string, [][]string) {
 	lineSize := len(columns)
 	if displayLineNum {
 		lineSize++
 	}
-	if skipHeader {
-		return lineSize, [][]string{}
-	}
+	titles := make([]string, 0, lineSize)
 	if displayLineNum {
-		header := make([]string, lineSize)
-		header[0] = "#"
-		copy(header[1:], columns)
-		return lineSize, [][]string{header}
+		titles = append(titles, "#")
+	}
+	for _, column := range columns {
+		titles = append(titles, column.title)
+	}
+	if skipHeader {
+		return titles, [][]string{}
 	}
-	return lineSize, [][]string{columns}
+	return titles, [][]string{titles}
 }
 
-func appendLine(table [][]string, line []string, columns []string, jsonObject map[string]any) [][]string {
-	for _, column := range columns {
-		line = append(line, common.ExtractString(jsonObject, column))
+func appendLine(table [][]string, line []string, values []any, placeholder string) [][]string {
+	for _, value := range values {
+		line = append(line, common.ToString(value, placeholder))
 	}
 	return append(table, line)
 }
 
-func displayTable(numColumns int, builder tableBuilder, table [][]string) error {
-	maxColumnSizes := make([]int, numColumns)
+// colors are given only for data lines, footer is optional
+func displayTable(titles []string, skipHeader bool, builder tableBuilder, table [][]string, colors [][]string, footer []string) error {
+	if len(table) == 0 {
+		return nil
+	}
+
+	dataTable := table
+	if !skipHeader {
+		dataTable = table[1:]
+	}
+	aligns := computeAligns(titles, dataTable)
+
+	hasFooter := footer != nil
+	if hasFooter {
+		table = append(table, footer)
+	}
+
+	maxColumnSizes := make([]int, len(titles))
 	for _, line := range table {
 		for index, value := range line {
 			maxColumnSizes[index] = max(len([]rune(value)), maxColumnSizes[index])
 		}
 	}
 
-	output := builder(maxColumnSizes, table)
+	layout := tableLayout{
+		titles:         titles,
+		hasHeader:      !skipHeader,
+		maxColumnSizes: maxColumnSizes,
+		aligns:         aligns,
+		colors:         completeColors(colors, len(titles), !skipHeader, len(table)),
+		hasFooter:      hasFooter,
+	}
+
+	if interactive {
+		return runViewer(builder, layout, table, frozenLineCount(layout.hasHeader))
+	}
+
+	output := builder(layout, table)
 	_, err := os.Stdout.WriteString(output)
 	return err
 }
 
-func buildTable(displayHeader bool, maxColumnSizes []int, table [][]string) string {
-	interline := buildInterline(maxColumnSizes)
+func buildTable(style frameStyle, rowSeparator bool, layout tableLayout, table [][]string) string {
+	interline := buildInterline(style.middle, layout.maxColumnSizes)
 
 	var outputBuilder strings.Builder
-	outputBuilder.WriteString(interline)
+	outputBuilder.WriteString(buildInterline(style.top, layout.maxColumnSizes))
 	tableSize := len(table)
-	writeTableLine(&outputBuilder, table[0], maxColumnSizes)
-	if displayHeader {
-		outputBuilder.WriteString(interline)
-	}
+	writeTableLine(&outputBuilder, style, layout, table, 0)
 	for index := 1; index < tableSize; index++ {
-		writeTableLine(&outputBuilder, table[index], maxColumnSizes)
+		// at most one interline before each line (after header, between rows or before footer)
+		if rowSeparator || (index == 1 && layout.hasHeader) || (layout.hasFooter && index == tableSize-1) {
+			outputBuilder.WriteString(interline)
+		}
+		writeTableLine(&outputBuilder, style, layout, table, index)
 	}
-	outputBuilder.WriteString(interline)
+	outputBuilder.WriteString(buildInterline(style.bottom, layout.maxColumnSizes))
 	return outputBuilder.String()
 }
 
-func buildInterline(maxColumnSizes []int) string {
-	var builder strings.Builder
-	builder.WriteByte('+')
-	for _, maxColumnSize := range maxColumnSizes {
-		builder.WriteByte('-')
-		for counter := 0; counter < maxColumnSize; counter++ {
-			builder.WriteByte('-')
+func writeTableLine(outputBuilder *strings.Builder, style frameStyle, layout tableLayout, table [][]string, lineIndex int) {
+	outputBuilder.WriteString(style.left)
+	for index, value := range table[lineIndex] {
+		if index != 0 {
+			outputBuilder.WriteString(style.inner)
 		}
-		builder.WriteString("-+")
-	}
-	builder.WriteByte('\n')
-	return builder.String()
-}
-
-func writeTableLine(outputBuilder *strings.Builder, line []string, maxColumnSizes []int) {
-	outputBuilder.WriteByte('|')
-	for index, value := range line {
 		outputBuilder.WriteByte(' ')
-		outputBuilder.WriteString(value)
-		for counter := len([]rune(value)); counter < maxColumnSizes[index]; counter++ {
-			outputBuilder.WriteByte(' ')
-		}
-		outputBuilder.WriteString(" |")
+		writeAligned(outputBuilder, value, layout.maxColumnSizes[index], layout.aligns[index], cellColor(layout.colors, lineIndex, index))
+		outputBuilder.WriteByte(' ')
 	}
+	outputBuilder.WriteString(style.right)
 	outputBuilder.WriteByte('\n')
 }
 
-func buildLines(maxColumnSizes []int, table [][]string) string {
+func buildLines(layout tableLayout, table [][]string) string {
 	var outputBuilder strings.Builder
-	for _, line := range table {
+	for lineIndex, line := range table {
 		for index, value := range line {
-			outputBuilder.WriteString(value)
-			for counter := len([]rune(value)); counter < maxColumnSizes[index]; counter++ {
-				outputBuilder.WriteByte(' ')
-			}
+			writeAligned(&outputBuilder, value, layout.maxColumnSizes[index], layout.aligns[index], cellColor(layout.colors, lineIndex, index))
 			outputBuilder.WriteByte(' ')
 		}
 		outputBuilder.WriteByte('\n')
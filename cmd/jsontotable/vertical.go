@@ -0,0 +1,142 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// data lines of the table (without header and footer) and footer (nil when absent)
+func splitTable(layout tableLayout, table [][]string) ([][]string, []string) {
+	if layout.hasHeader {
+		table = table[1:]
+	}
+	if last := len(table) - 1; layout.hasFooter && last >= 0 {
+		return table[:last], table[last]
+	}
+	return table, nil
+}
+
+// attribute names are colored as header, values keep the color of their cell
+func verticalColors(layout tableLayout, lineIndex int) (string, []string) {
+	if layout.colors == nil {
+		return "", nil
+	}
+	if layout.hasHeader {
+		lineIndex++
+	}
+	return boldCode, layout.colors[lineIndex]
+}
+
+func verticalSizes(layout tableLayout, table [][]string) (int, int) {
+	nameSize, valueSize := 0, 0
+	for _, title := range layout.titles {
+		nameSize = max(len([]rune(title)), nameSize)
+	}
+	for _, line := range table {
+		for _, value := range line {
+			valueSize = max(len([]rune(value)), valueSize)
+		}
+	}
+	return nameSize, valueSize
+}
+
+func recordTitle(index int) string {
+	return "RECORD " + strconv.Itoa(index)
+}
+
+func buildVerticalTable(style frameStyle, layout tableLayout, table [][]string) string {
+	lines, footer := splitTable(layout, table)
+	nameSize, valueSize := verticalSizes(layout, table)
+	nameSize = max(nameSize, len(recordTitle(len(lines))), len("FOOTER")) // record title can be larger
+	sizes := []int{nameSize, valueSize}
+
+	titleStyle := style.top
+	titleStyle.cross = titleStyle.fill
+	underTitleStyle := style.middle
+	underTitleStyle.cross = style.top.cross
+	top := buildInterline(titleStyle, sizes)
+	underTitle := buildInterline(underTitleStyle, sizes)
+	bottom := buildInterline(style.bottom, sizes)
+	recordSize := nameSize + valueSize + len([]rune(style.inner)) + 2
+
+	var outputBuilder strings.Builder
+	writeBlock := func(title string, line []string, nameCode string, codes []string) {
+		outputBuilder.WriteString(top)
+		outputBuilder.WriteString(style.left)
+		outputBuilder.WriteByte(' ')
+		writeAligned(&outputBuilder, title, recordSize, alignLeft, nameCode)
+		outputBuilder.WriteByte(' ')
+		outputBuilder.WriteString(style.right)
+		outputBuilder.WriteByte('\n')
+		outputBuilder.WriteString(underTitle)
+		for index, value := range line {
+			outputBuilder.WriteString(style.left)
+			outputBuilder.WriteByte(' ')
+			writeAligned(&outputBuilder, layout.titles[index], nameSize, alignLeft, nameCode)
+			outputBuilder.WriteByte(' ')
+			outputBuilder.WriteString(style.inner)
+			outputBuilder.WriteByte(' ')
+			writeAligned(&outputBuilder, value, valueSize, alignLeft, lineColor(codes, index))
+			outputBuilder.WriteByte(' ')
+			outputBuilder.WriteString(style.right)
+			outputBuilder.WriteByte('\n')
+		}
+		outputBuilder.WriteString(bottom)
+	}
+
+	for lineIndex, line := range lines {
+		nameCode, codes := verticalColors(layout, lineIndex)
+		writeBlock(recordTitle(lineIndex), line, nameCode, codes)
+	}
+	if footer != nil {
+		nameCode, codes := verticalColors(layout, len(lines))
+		writeBlock("FOOTER", footer, nameCode, codes)
+	}
+	return outputBuilder.String()
+}
+
+func buildVerticalLines(layout tableLayout, table [][]string) string {
+	lines, footer := splitTable(layout, table)
+	nameSize, valueSize := verticalSizes(layout, table)
+
+	var outputBuilder strings.Builder
+	writeBlock := func(title string, line []string, nameCode string, codes []string) {
+		outputBuilder.WriteString("-[ ")
+		outputBuilder.WriteString(title)
+		outputBuilder.WriteString(" ]-\n")
+		for index, value := range line {
+			writeAligned(&outputBuilder, layout.titles[index], nameSize, alignLeft, nameCode)
+			outputBuilder.WriteByte(' ')
+			writeAligned(&outputBuilder, value, valueSize, alignLeft, lineColor(codes, index))
+			outputBuilder.WriteByte('\n')
+		}
+	}
+
+	for lineIndex, line := range lines {
+		nameCode, codes := verticalColors(layout, lineIndex)
+		writeBlock(recordTitle(lineIndex), line, nameCode, codes)
+	}
+	if footer != nil {
+		nameCode, codes := verticalColors(layout, len(lines))
+		writeBlock("FOOTER", footer, nameCode, codes)
+	}
+	return outputBuilder.String()
+}
@@ -0,0 +1,359 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+const (
+	scrollStep = 8
+
+	keyUp       = "\x1b[A"
+	keyDown     = "\x1b[B"
+	keyRight    = "\x1b[C"
+	keyLeft     = "\x1b[D"
+	keyPageUp   = "\x1b[5~"
+	keyPageDown = "\x1b[6~"
+	keyEscape   = "\x1b"
+	keyEnter    = "\r"
+	keyCtrlC    = "\x03"
+
+	helpMessage = "q:quit j/k:line space/b:page h/l:scroll /:search n/N:next/previous [/]:column s:sort v:version sort"
+)
+
+var (
+	errNotTerminal = errors.New("interactive mode requires a terminal")
+
+	keyAliases = map[string]string{
+		"\x1b[H": "g", "\x1b[1~": "g", "\x1bOH": "g",
+		"\x1b[F": "G", "\x1b[4~": "G", "\x1bOF": "G",
+		keyUp: "k", keyDown: "j", keyRight: "l", keyLeft: "h",
+		keyPageUp: "b", keyPageDown: " ",
+	}
+
+	interactive bool
+)
+
+type viewer struct {
+	builder    tableBuilder
+	layout     tableLayout
+	table      [][]string
+	frozen     int // number of header lines kept on top
+	lines      []string
+	top        int
+	left       int
+	width      int
+	height     int
+	selected   int
+	sortColumn int
+	sortDesc   bool
+	searching  bool
+	search     string
+	searchTop  int // position when search started
+	message    string
+}
+
+func runViewer(builder tableBuilder, layout tableLayout, table [][]string, frozen int) error {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return errNotTerminal
+	}
+
+	tty, err := common.OpenTerminal()
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+
+	state, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(tty.Fd()), state)
+
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l") // alternate screen and hidden cursor
+	defer os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+
+	v := &viewer{builder: builder, layout: layout, table: table, frozen: frozen, sortColumn: -1}
+	v.render()
+	buffer := make([]byte, 16)
+	for {
+		if v.width, v.height, err = term.GetSize(fd); err != nil {
+			return err
+		}
+		v.draw()
+
+		n, err := tty.Read(buffer)
+		if err != nil {
+			return err
+		}
+		if !v.handleKey(string(buffer[:n])) {
+			return nil
+		}
+	}
+}
+
+func (v *viewer) render() {
+	v.lines = strings.Split(strings.TrimSuffix(v.builder(v.layout, v.table), "\n"), "\n")
+	v.frozen = min(v.frozen, len(v.lines))
+}
+
+func (v *viewer) bodyHeight() int {
+	return max(v.height-v.frozen-1, 1) // last line is for status
+}
+
+func (v *viewer) maxTop() int {
+	return max(len(v.lines)-v.frozen-v.bodyHeight(), 0)
+}
+
+func (v *viewer) maxLeft() int {
+	maxWidth := 0
+	for _, line := range v.lines {
+		maxWidth = max(len([]rune(line)), maxWidth)
+	}
+	return max(maxWidth-v.width, 0)
+}
+
+func (v *viewer) draw() {
+	var builder strings.Builder
+	builder.WriteString("\x1b[H")
+	for _, line := range v.lines[:v.frozen] {
+		v.writeLine(&builder, line)
+	}
+
+	body := v.lines[v.frozen:]
+	for index, end := v.top, v.top+v.bodyHeight(); index < end; index++ {
+		if index < len(body) {
+			v.writeLine(&builder, body[index])
+		} else {
+			v.writeLine(&builder, "~")
+		}
+	}
+
+	builder.WriteString("\x1b[7m")
+	builder.WriteString(clip(v.status(), 0, v.width))
+	builder.WriteString("\x1b[K\x1b[0m")
+	os.Stdout.WriteString(builder.String())
+}
+
+func (v *viewer) writeLine(builder *strings.Builder, line string) {
+	builder.WriteString(clip(line, v.left, v.width))
+	builder.WriteString("\x1b[K\r\n")
+}
+
+func (v *viewer) status() string {
+	if v.searching {
+		return "/" + v.search
+	}
+
+	var builder strings.Builder
+	bodySize := len(v.lines) - v.frozen
+	fmt.Fprintf(&builder, "lines %d-%d/%d", min(v.top+1, bodySize), min(v.top+v.bodyHeight(), bodySize), bodySize)
+	if len(v.layout.titles) != 0 {
+		builder.WriteString(" | column ")
+		builder.WriteString(v.layout.titles[v.selected])
+	}
+	if v.sortColumn != -1 {
+		builder.WriteString(" | sorted by ")
+		builder.WriteString(v.layout.titles[v.sortColumn])
+		if v.sortDesc {
+			builder.WriteString(" desc")
+		}
+	}
+	if v.message != "" {
+		builder.WriteString(" | ")
+		builder.WriteString(v.message)
+	}
+	builder.WriteString(" | ")
+	builder.WriteString(helpMessage)
+	return builder.String()
+}
+
+// return false to quit
+func (v *viewer) handleKey(key string) bool {
+	if v.searching {
+		v.handleSearchKey(key)
+		return true
+	}
+
+	v.message = ""
+	if alias, ok := keyAliases[key]; ok {
+		key = alias
+	}
+	switch key {
+	case "q", keyCtrlC:
+		return false
+	case "j":
+		v.top = min(v.top+1, v.maxTop())
+	case "k":
+		v.top = max(v.top-1, 0)
+	case " ", "f":
+		v.top = min(v.top+v.bodyHeight(), v.maxTop())
+	case "b":
+		v.top = max(v.top-v.bodyHeight(), 0)
+	case "g":
+		v.top = 0
+	case "G":
+		v.top = v.maxTop()
+	case "l":
+		v.left = min(v.left+scrollStep, v.maxLeft())
+	case "h":
+		v.left = max(v.left-scrollStep, 0)
+	case "]":
+		if numColumns := len(v.layout.titles); numColumns != 0 {
+			v.selected = (v.selected + 1) % numColumns
+		}
+	case "[":
+		if numColumns := len(v.layout.titles); numColumns != 0 {
+			v.selected = (v.selected + numColumns - 1) % numColumns
+		}
+	case "s":
+		v.sort(false)
+	case "v":
+		v.sort(true)
+	case "/":
+		v.searching, v.search, v.searchTop = true, "", v.top
+	case "n":
+		v.find(v.top+1, 1)
+	case "N":
+		v.find(v.top-1, -1)
+	}
+	return true
+}
+
+// incremental search, escape restore previous position
+func (v *viewer) handleSearchKey(key string) {
+	switch key {
+	case keyEnter:
+		v.searching = false
+		return
+	case keyEscape, keyCtrlC:
+		v.searching, v.search, v.top = false, "", v.searchTop
+		return
+	case "\x7f", "\b":
+		if runes := []rune(v.search); len(runes) != 0 {
+			v.search = string(runes[:len(runes)-1])
+		}
+	default:
+		if strings.HasPrefix(key, keyEscape) || key[0] < ' ' {
+			return
+		}
+		v.search += key
+	}
+	v.top = v.searchTop
+	v.find(v.searchTop, 1)
+}
+
+func (v *viewer) find(start int, step int) {
+	if v.search == "" {
+		return
+	}
+
+	search := strings.ToLower(v.search)
+	body := v.lines[v.frozen:]
+	for index := start; index >= 0 && index < len(body); index += step {
+		if strings.Contains(strings.ToLower(body[index]), search) {
+			v.top = min(index, v.maxTop())
+			return
+		}
+	}
+	v.message = "pattern not found"
+}
+
+// same column sort again toggle order, numeric columns are compared as number
+func (v *viewer) sort(asVersion bool) {
+	if len(v.layout.titles) == 0 {
+		return
+	}
+
+	if v.sortColumn == v.selected {
+		v.sortDesc = !v.sortDesc
+	} else {
+		v.sortColumn, v.sortDesc = v.selected, false
+	}
+
+	start, end := 0, len(v.table)
+	if v.layout.hasHeader {
+		start++
+	}
+	if v.layout.hasFooter {
+		end--
+	}
+	dataTable := v.table[start:end]
+
+	column := v.sortColumn
+	var cmpLine func([]string, []string) int
+	switch {
+	case asVersion:
+		cmpLine = func(a []string, b []string) int {
+			return common.CompareVersion(common.VersionKey(a[column]), common.VersionKey(b[column]))
+		}
+	case isNumericColumn(column, dataTable):
+		cmpLine = func(a []string, b []string) int {
+			return common.NumberKey(a[column]).Cmp(common.NumberKey(b[column]))
+		}
+	default:
+		cmpLine = func(a []string, b []string) int {
+			return cmp.Compare(a[column], b[column])
+		}
+	}
+	if v.sortDesc {
+		ascCmp := cmpLine
+		cmpLine = func(a []string, b []string) int {
+			return ascCmp(b, a)
+		}
+	}
+
+	slices.SortStableFunc(dataTable, cmpLine)
+	v.render()
+}
+
+// keep at most width runes after skipping offset ones
+func clip(line string, offset int, width int) string {
+	runes := []rune(line)
+	if offset >= len(runes) {
+		return ""
+	}
+	runes = runes[offset:]
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	return string(runes)
+}
+
+// number of lines to keep on top of the interactive view
+func frozenLineCount(hasHeader bool) int {
+	switch {
+	case !hasHeader || vertical:
+		return 0
+	case simple:
+		return 1
+	}
+	return 3 // top frame line, header and separator
+}
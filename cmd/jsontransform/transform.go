@@ -44,7 +44,7 @@ type Rule struct {
 }
 
 func makeRule(name string, expression string) (Rule, error) {
-	prog, err := expr.Compile(expression)
+	prog, err := common.CompileExpr(expression)
 	if err != nil {
 		return Rule{}, err
 	}
@@ -63,7 +63,8 @@ func main() {
 		Short: "jsontransform transform JSON object from FILE with EXPRESSION as rules.",
 		Long: `jsontransform transform JSON object from FILE with EXPRESSION as rules,
 if FILE is -, read from standard input,
-to know which EXPRESSION is accepted : see https://expr-lang.org/docs/language-definition`,
+to know which EXPRESSION is accepted : see https://expr-lang.org/docs/language-definition,
+JSON integers are exact (even beyond int64 range), helpers number(x) and cmpNumber(x, y) (exact on numeric strings) are available`,
 		Args: cobra.MinimumNArgs(2),
 		RunE: jsonTransformWithInit,
 	}
@@ -109,11 +110,15 @@ func jsonTransform(rules []Rule, jsonParser func([]byte) (any, error), src *os.F
 			return err
 		}
 
-		newObject := map[string]any{}
+		orders := common.FieldOrders{}
+		exprValue := common.ToExprValue(jsonValue, orders)
+		newObject := common.NewObject(len(rules))
 		for _, rule := range rules {
-			if newObject[rule.Name], err = rule.Transform(jsonValue); err != nil {
+			value, err := rule.Transform(exprValue)
+			if err != nil {
 				return err
 			}
+			newObject.Set(rule.Name, orders.Restore(value))
 		}
 
 		if err = encoder.Encode(newObject); err != nil {
@@ -143,20 +148,19 @@ func parseRules(expressions []string) ([]Rule, error) {
 }
 
 func jsonParser(data []byte) (any, error) {
-	var jsonValue any
-	err := json.Unmarshal(data, &jsonValue)
-	return jsonValue, err
+	return common.Decode(data)
 }
 
 func underlineJsonParser(data []byte) (any, error) {
-	var jsonObject map[string]any
-	if err := json.Unmarshal(data, &jsonObject); err != nil {
+	jsonObject, err := common.DecodeObject(data)
+	if err != nil {
 		return nil, err
 	}
 
-	newObject := map[string]any{}
-	for name, value := range jsonObject {
-		newObject[strings.ReplaceAll(name, " ", "_")] = value
+	newObject := common.NewObject(jsonObject.Len())
+	for _, name := range jsonObject.Names() {
+		value, _ := jsonObject.Get(name)
+		newObject.Set(strings.ReplaceAll(name, " ", "_"), value)
 	}
 	return newObject, nil
 }
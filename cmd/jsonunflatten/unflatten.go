@@ -0,0 +1,157 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dvaumoron/shelltools/pkg/common"
+)
+
+var (
+	objectOnly bool
+	separator  string
+)
+
+func main() {
+	cmd := cobra.Command{
+		Use:   "jsonunflatten [FILE]",
+		Short: "jsonunflatten convert flat JSON object from FILE in nested JSON object.",
+		Long: `jsonunflatten convert flat JSON object from FILE in nested JSON object,
+without FILE or if FILE is -, read from standard input,
+field name are splitted with separator to build nested path (like 'a.b.c'),
+nested object with only consecutive index as field name are converted to array`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: jsonUnflattenWithInit,
+	}
+
+	cmdFlags := cmd.Flags()
+	cmdFlags.StringVarP(&separator, "separator", "s", ".", "separator between path segments")
+	cmdFlags.BoolVarP(&objectOnly, "no-array", "o", false, "do not convert indexed object to array")
+
+	if err := cmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func jsonUnflattenWithInit(cmd *cobra.Command, args []string) error {
+	src, closer, err := common.GetSource(args, 0)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	return jsonUnflatten(src, !objectOnly)
+}
+
+func jsonUnflatten(src *os.File, rebuildArray bool) error {
+	scanner := bufio.NewScanner(src)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		jsonObject, err := common.DecodeObject(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+
+		nestedObject := common.NewObject(jsonObject.Len())
+		for _, name := range jsonObject.Names() {
+			value, _ := jsonObject.Get(name)
+			if err := insertValue(nestedObject, strings.Split(name, separator), value); err != nil {
+				return err
+			}
+		}
+
+		if rebuildArray {
+			// top level stay an object
+			for _, name := range nestedObject.Names() {
+				subValue, _ := nestedObject.Get(name)
+				nestedObject.Set(name, toArray(subValue))
+			}
+		}
+		if err := encoder.Encode(nestedObject); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func insertValue(nestedObject *common.Object, path []string, value any) error {
+	last := len(path) - 1
+	for index, segment := range path[:last] {
+		subValue, ok := nestedObject.Get(segment)
+		if !ok {
+			subObject := common.NewObject(0)
+			nestedObject.Set(segment, subObject)
+			nestedObject = subObject
+			continue
+		}
+
+		switch casted := subValue.(type) {
+		case *common.Object:
+			nestedObject = casted
+		default:
+			return fmt.Errorf("conflicting value for path %q", strings.Join(path[:index+1], separator))
+		}
+	}
+
+	segment := path[last]
+	if _, ok := nestedObject.Get(segment); ok {
+		return fmt.Errorf("conflicting value for path %q", strings.Join(path, separator))
+	}
+	nestedObject.Set(segment, value)
+	return nil
+}
+
+// convert recursively map with keys "0" to "n-1" into array
+func toArray(value any) any {
+	jsonObject, ok := value.(*common.Object)
+	if !ok {
+		return value
+	}
+
+	names := jsonObject.Names()
+	for _, name := range names {
+		subValue, _ := jsonObject.Get(name)
+		jsonObject.Set(name, toArray(subValue))
+	}
+
+	size := len(names)
+	if size == 0 {
+		return jsonObject
+	}
+
+	array := make([]any, size)
+	for _, name := range names {
+		subValue, _ := jsonObject.Get(name)
+		index, err := strconv.Atoi(name)
+		if err != nil || index < 0 || index >= size || strconv.Itoa(index) != name {
+			return jsonObject
+		}
+		array[index] = subValue
+	}
+	return array
+}
@@ -20,11 +20,9 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 
-	"github.com/expr-lang/expr"
 	"github.com/spf13/cobra"
 
 	"github.com/dvaumoron/shelltools/pkg/common"
@@ -36,7 +34,8 @@ func main() {
 		Short: "jsonwhere filter JSON object from FILE with EXPRESSION as predicate.",
 		Long: `jsonwhere filter JSON object from FILE with EXPRESSION as predicate,
 without FILE or if FILE is -, read from standard input,
-to know which EXPRESSION is accepted : see https://expr-lang.org/docs/language-definition`,
+to know which EXPRESSION is accepted : see https://expr-lang.org/docs/language-definition,
+JSON integers are exact (even beyond int64 range), helpers number(x) and cmpNumber(x, y) (exact on numeric strings) are available`,
 		Args: cobra.RangeArgs(1, 2),
 		RunE: jsonWhereWithInit,
 	}
@@ -48,7 +47,7 @@ to know which EXPRESSION is accepted : see https://expr-lang.org/docs/language-d
 }
 
 func jsonWhereWithInit(cmd *cobra.Command, args []string) error {
-	pred, err := parsePredicate(args[0])
+	pred, err := common.ParsePredicate(args[0])
 	if err != nil {
 		return err
 	}
@@ -62,29 +61,16 @@ func jsonWhereWithInit(cmd *cobra.Command, args []string) error {
 	return jsonWhere(pred, src)
 }
 
-func parsePredicate(expression string) (func(any) bool, error) {
-	prog, err := expr.Compile(expression)
-	if err != nil {
-		return nil, err
-	}
-
-	return func(value any) bool {
-		output, _ := expr.Run(prog, value)
-		casted, _ := output.(bool)
-		return casted
-	}, nil
-}
-
 func jsonWhere(pred func(any) bool, src *os.File) error {
 	endLine := []byte{'\n'}
 	scanner := bufio.NewScanner(src)
 	for scanner.Scan() {
 		b := scanner.Bytes()
-		var jsonValue any
-		if err := json.Unmarshal(b, &jsonValue); err != nil {
+		jsonValue, err := common.Decode(b)
+		if err != nil {
 			return err
 		}
-		if pred(jsonValue) {
+		if pred(common.ToExprValue(jsonValue, nil)) {
 			if _, err := os.Stdout.Write(b); err != nil {
 				return err
 			}
@@ -153,11 +153,11 @@ func lineToJson(namer columnNamer, splitter func(string) []string, src *os.File)
 	return scanner.Err()
 }
 
-func toJsonObject(splitted []string, namer columnNamer) map[string]string {
-	jsonObject := make(map[string]string, len(splitted))
+func toJsonObject(splitted []string, namer columnNamer) *common.Object {
+	jsonObject := common.NewObject(len(splitted))
 	for index, value := range splitted {
 		if value != "" {
-			jsonObject[namer.Name(index)] = value
+			jsonObject.Set(namer.Name(index), value)
 		}
 	}
 	return jsonObject
@@ -7,9 +7,12 @@ require (
 	github.com/hashicorp/go-version v1.7.0
 	github.com/spf13/cobra v1.8.1
 	github.com/tofuutils/tenv/v2 v2.7.9
+	golang.org/x/term v0.29.0
+	golang.org/x/text v0.22.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/sys v0.30.0 // indirect
 )
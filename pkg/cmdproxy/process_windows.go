@@ -0,0 +1,44 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmdproxy
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func setOwnGroup(cmd *exec.Cmd) {}
+
+// windows does not support SIGTERM
+func signalProcess(process *os.Process, ownGroup bool, sig os.Signal) error {
+	if sig == os.Kill || sig == syscall.SIGTERM {
+		return process.Kill()
+	}
+	return process.Signal(sig)
+}
+
+func hasTerminal() bool {
+	tty, err := os.Open("CONIN$")
+	if err != nil {
+		return false
+	}
+	tty.Close()
+	return true
+}
@@ -19,21 +19,201 @@
 package cmdproxy
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"os/exec"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// exit code reported when a command is stopped by timeout (same as GNU timeout)
+const TimeoutExitCode = 124
+
+const maxBackoffShift = 20
+
+var controllingTerminal = sync.OnceValue(hasTerminal)
+
+type Result struct {
+	ExitCode int
+	TimedOut bool
+	Attempts int
+	Duration time.Duration
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// the zero value run each command once without time limit
+type Runner struct {
+	// on timeout, the command receive SIGTERM, then SIGKILL after KillDelay
+	Timeout   time.Duration
+	KillDelay time.Duration
+	// number of additional attempts after a failure
+	Retries int
+	// exit codes triggering a retry (any failure when empty)
+	RetryOn []int
+	// delay before the first retry, doubled for each following one (with jitter)
+	Backoff time.Duration
+	// standard input of command (os.Stdin when nil), rewound before each attempt when possible
+	Stdin io.Reader
+	// working directory of command (current one when empty)
+	Dir string
+	// additional environment variables (NAME=VALUE)
+	Env []string
+}
+
 func Run(cmdName string, cmdArgs []string) {
-	cmd := exec.Command(cmdName, cmdArgs...)
-	cmd.Stderr = os.Stderr
+	Runner{}.Run(cmdName, cmdArgs)
+}
+
+func Capture(cmdName string, cmdArgs []string) (Result, error) {
+	return Runner{}.Capture(cmdName, cmdArgs)
+}
+
+func (r Runner) Run(cmdName string, cmdArgs []string) {
+	result, err := r.retry(cmdName, cmdArgs, func(cmd *exec.Cmd) {
+		cmd.Stderr = os.Stderr
+		cmd.Stdout = os.Stdout
+	})
+
+	if errors.Is(err, ErrStopped) || Stopped() {
+		return // ending is handled by signal receiver
+	}
+	if err != nil {
+		fmt.Println("Failure during", cmdName, "call :", err)
+		return
+	}
+	if result.TimedOut {
+		fmt.Println("Timeout during", cmdName, "call")
+	}
+	if result.ExitCode != 0 {
+		os.Exit(result.ExitCode)
+	}
+}
+
+// run without stopping on failure, error is only returned when the command could not be started
+func (r Runner) Capture(cmdName string, cmdArgs []string) (Result, error) {
+	var stdout, stderr bytes.Buffer
+	result, err := r.retry(cmdName, cmdArgs, func(cmd *exec.Cmd) {
+		// only keep output of last attempt
+		stderr.Reset()
+		stdout.Reset()
+		cmd.Stderr = &stderr
+		cmd.Stdout = &stdout
+	})
+
+	result.Stdout, result.Stderr = stdout.Bytes(), stderr.Bytes()
+	return result, err
+}
+
+func (r Runner) retry(cmdName string, cmdArgs []string, prepare func(*exec.Cmd)) (Result, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := r.runOnce(cmdName, cmdArgs, prepare)
+		result.Attempts = attempt + 1
+		if err != nil || attempt >= r.Retries || !r.retryable(result.ExitCode) {
+			return result, err
+		}
+
+		select {
+		case <-time.After(r.backoff(attempt)):
+		case <-Done():
+			return result, nil
+		}
+	}
+}
+
+func (r Runner) runOnce(cmdName string, cmdArgs []string, prepare func(*exec.Cmd)) (Result, error) {
+	if Stopped() {
+		return Result{ExitCode: -1}, ErrStopped
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if r.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+	}
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, cmdName, cmdArgs...)
+	cmd.Dir = r.Dir
+	if len(r.Env) != 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+	if r.Stdin != nil {
+		if seeker, ok := r.Stdin.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return Result{ExitCode: -1}, err
+			}
+		}
+		cmd.Stdin = r.Stdin
+	}
+	prepare(cmd)
 
-	if err := cmd.Run(); err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitError.ExitCode())
+	// with a controlling terminal, commands must stay in the foreground process group
+	// (a command reading /dev/tty from a background group would be stopped)
+	ownGroup := !controllingTerminal()
+	if ownGroup {
+		setOwnGroup(cmd)
+	}
+
+	var killTimer *time.Timer
+	if r.Timeout > 0 {
+		cmd.Cancel = func() error {
+			killTimer = time.AfterFunc(r.KillDelay, func() {
+				signalProcess(cmd.Process, ownGroup, os.Kill)
+			})
+			return signalProcess(cmd.Process, ownGroup, syscall.SIGTERM)
 		}
-		fmt.Println("Failure during", cmdName, "call :", err)
+		cmd.WaitDelay = r.KillDelay
+	}
+
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		addChild(cmd.Process, ownGroup)
+		err = cmd.Wait()
+		removeChild(cmd.Process)
+	}
+	if killTimer != nil { // Wait return after Cancel
+		killTimer.Stop()
+	}
+
+	// a background process can hold output after a successful exit
+	success := cmd.ProcessState != nil && cmd.ProcessState.Success()
+	result := Result{Duration: time.Since(start), TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded) && !success}
+	switch {
+	case result.TimedOut:
+		result.ExitCode = TimeoutExitCode
+	case success && errors.Is(err, exec.ErrWaitDelay):
+	case err != nil:
+		var exitError *exec.ExitError
+		if !errors.As(err, &exitError) {
+			result.ExitCode = -1
+			return result, err
+		}
+		result.ExitCode = exitError.ExitCode()
+	}
+	return result, nil
+}
+
+func (r Runner) retryable(exitCode int) bool {
+	if exitCode == 0 {
+		return false
+	}
+	return len(r.RetryOn) == 0 || slices.Contains(r.RetryOn, exitCode)
+}
+
+// exponential delay, randomized between its half and its full value
+func (r Runner) backoff(attempt int) time.Duration {
+	delay := r.Backoff << min(attempt, maxBackoffShift)
+	if half := delay / 2; half > 0 {
+		return half + time.Duration(rand.Int63n(int64(half)+1))
 	}
+	return delay
 }
@@ -0,0 +1,96 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package cmdproxy
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+var ErrStopped = errors.New("stopped by signal")
+
+var (
+	childrenMutex sync.Mutex
+	children      = map[*os.Process]bool{} // true when the process has its own group
+
+	stopOnce sync.Once
+	stopped  = make(chan struct{})
+)
+
+// forward received signals to running commands, the first one also prevents new commands to start,
+// the returned function restores default signal handling
+// (SIGINT is not forwarded to commands in the foreground process group, the terminal already sent it)
+func ForwardSignals(signals ...os.Signal) func() {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	go func() {
+		for sig := range received {
+			stopOnce.Do(func() {
+				close(stopped)
+			})
+			signalChildren(sig)
+		}
+	}()
+
+	return func() {
+		signal.Stop(received)
+		close(received)
+	}
+}
+
+// closed when a forwarded signal has been received
+func Done() <-chan struct{} {
+	return stopped
+}
+
+func Stopped() bool {
+	select {
+	case <-stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+func addChild(process *os.Process, ownGroup bool) {
+	childrenMutex.Lock()
+	defer childrenMutex.Unlock()
+
+	children[process] = ownGroup
+}
+
+func removeChild(process *os.Process) {
+	childrenMutex.Lock()
+	defer childrenMutex.Unlock()
+
+	delete(children, process)
+}
+
+func signalChildren(sig os.Signal) {
+	childrenMutex.Lock()
+	defer childrenMutex.Unlock()
+
+	for process, ownGroup := range children {
+		if ownGroup || sig != os.Interrupt {
+			signalProcess(process, ownGroup, sig) // error ignored, the process may have already ended
+		}
+	}
+}
@@ -20,13 +20,48 @@ package common
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 )
 
-func ExtractString(jsonObject map[string]any, column string) string {
-	return fmt.Sprint(jsonObject[column])
+// missing and null value are displayed as placeholder
+func ExtractString(jsonObject *Object, path Path, placeholder string) string {
+	value, _ := path.Extract(jsonObject)
+	return ToString(value, placeholder)
+}
+
+func ToString(value any, placeholder string) string {
+	switch casted := value.(type) {
+	case nil:
+		return placeholder
+	case string:
+		return casted
+	case json.Number:
+		return casted.String()
+	case bool:
+		return strconv.FormatBool(casted)
+	case float64:
+		return strconv.FormatFloat(casted, 'f', -1, 64)
+	case *Object, map[string]any, []any:
+		return compactJson(casted)
+	}
+	return fmt.Sprint(value)
+}
+
+func compactJson(value any) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSuffix(buffer.String(), "\n")
 }
 
 func GetSource(args []string, pos int) (*os.File, func() error, error) {
@@ -45,17 +80,60 @@ func GetSource(args []string, pos int) (*os.File, func() error, error) {
 	return src, closer, nil
 }
 
+// terminal input, usable even when standard input is redirected
+func OpenTerminal() (*os.File, error) {
+	ttyPath := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		ttyPath = "CONIN$"
+	}
+	return os.Open(ttyPath)
+}
+
 func noActionCloser() error {
 	return nil
 }
 
-func TrimmedLines(src *os.File) ([]string, error) {
-	splitted := []string{}
+// how lines are delimited (newline or NUL character, like find -print0) and cleaned
+type LineOptions struct {
+	NullDelimited bool
+	NoTrim        bool
+}
+
+func (o LineOptions) NewScanner(src io.Reader) *bufio.Scanner {
 	scanner := bufio.NewScanner(src)
-	for scanner.Scan() {
-		splitted = append(splitted, strings.TrimSpace(scanner.Text()))
+	if o.NullDelimited {
+		scanner.Split(scanNull)
+	}
+	return scanner
+}
+
+func (o LineOptions) Text(scanner *bufio.Scanner) string {
+	if o.NoTrim {
+		return scanner.Text()
+	}
+	return strings.TrimSpace(scanner.Text())
+}
+
+// delimiter to use in output
+func (o LineOptions) Delimiter() string {
+	if o.NullDelimited {
+		return "\x00"
+	}
+	return "\n"
+}
+
+// same as bufio.ScanLines with NUL character as delimiter (and no carriage return handling)
+func scanNull(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if index := bytes.IndexByte(data, 0); index >= 0 {
+		return index + 1, data[:index], nil
+	}
+	if atEOF {
+		return len(data), data, nil
 	}
-	return splitted, scanner.Err()
+	return 0, nil, nil
 }
 
 func TrimSlice(values []string) {
@@ -0,0 +1,52 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"math/big"
+
+	"github.com/hashicorp/go-version"
+)
+
+// exact value (no float64 rounding on large integer), 0 when not a number
+func NumberKey(value any) *big.Rat {
+	if number, ok := ToRat(value); ok {
+		return number
+	}
+	return new(big.Rat)
+}
+
+// nil when not a semantic version
+func VersionKey(value any) *version.Version {
+	v, _ := version.NewVersion(ToString(value, ""))
+	return v
+}
+
+// nil version are sorted first
+func CompareVersion(v1 *version.Version, v2 *version.Version) int {
+	switch {
+	case v1 == nil && v2 == nil:
+		return 0
+	case v1 == nil:
+		return -1
+	case v2 == nil:
+		return 1
+	}
+	return v1.Compare(v2)
+}
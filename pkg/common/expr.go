@@ -0,0 +1,324 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+	"github.com/expr-lang/expr/vm/runtime"
+)
+
+var (
+	errNotNumber = errors.New("value is not a number")
+	errParamNum  = errors.New("wrong number of parameters")
+
+	exprOptions = []expr.Option{
+		expr.Function("number", numberFunc, new(func(any) any)),
+		expr.Function("cmpNumber", cmpNumberFunc, new(func(any, any) int)),
+		expr.Function(numberOpName, numberOpFunc, new(func(string, any, any) any)),
+		expr.Patch(numberOpPatcher{}),
+	}
+
+	numberOperators = []string{"==", "!=", "<", ">", "<=", ">=", "+", "-", "*"}
+)
+
+const numberOpName = "_numberOp"
+
+// replace operators on untyped operands (JSON values) by a call to numberOpFunc
+type numberOpPatcher struct{}
+
+func (numberOpPatcher) Visit(node *ast.Node) {
+	binary, ok := (*node).(*ast.BinaryNode)
+	if !ok || !slices.Contains(numberOperators, binary.Operator) || !(untyped(binary.Left) || untyped(binary.Right)) {
+		return
+	}
+
+	ast.Patch(node, &ast.CallNode{
+		Callee:    &ast.IdentifierNode{Value: numberOpName},
+		Arguments: []ast.Node{&ast.StringNode{Value: binary.Operator}, binary.Left, binary.Right},
+	})
+}
+
+func untyped(node ast.Node) bool {
+	nodeType := node.Type()
+	return nodeType == nil || nodeType.Kind() == reflect.Interface
+}
+
+// compile with helpers for JSON number :
+// - number(x) convert a string or a number to int64, uint64, big integer or float64
+// - cmpNumber(x, y) compare exactly two numbers (return -1, 0 or 1)
+// (comparison and arithmetic operators are exact on uint64 and big integer)
+func CompileExpr(expression string) (*vm.Program, error) {
+	return expr.Compile(expression, exprOptions...)
+}
+
+// value must be converted with ToExprValue, a failing evaluation is false
+// (each distinct error is reported once on stderr)
+func ParsePredicate(expression string) (func(any) bool, error) {
+	prog, err := CompileExpr(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var reported sync.Map
+	return func(value any) bool {
+		output, err := expr.Run(prog, value)
+		if err != nil {
+			if _, loaded := reported.LoadOrStore(err.Error(), struct{}{}); !loaded {
+				fmt.Fprintln(os.Stderr, "Failure during", expression, "evaluation :", err)
+			}
+			return false
+		}
+		casted, _ := output.(bool)
+		return casted
+	}, nil
+}
+
+// convert recursively *Object to map (field order are recorded when orders is not nil)
+// and json.Number to int64, uint64, big integer or float64 (when not an integer)
+func ToExprValue(value any, orders FieldOrders) any {
+	switch casted := value.(type) {
+	case json.Number:
+		return convertNumber(string(casted))
+	case *Object:
+		jsonMap := make(map[string]any, casted.Len())
+		for _, name := range casted.names {
+			jsonMap[name] = ToExprValue(casted.values[name], orders)
+		}
+		orders.record(jsonMap, casted.names)
+		return jsonMap
+	case []any:
+		array := make([]any, len(casted))
+		for index, subValue := range casted {
+			array[index] = ToExprValue(subValue, orders)
+		}
+		return array
+	}
+	return value
+}
+
+func convertNumber(number string) any {
+	if i, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(number, 10, 64); err == nil {
+		return u
+	}
+	if bigInt, ok := new(big.Int).SetString(number, 10); ok {
+		return bigInt
+	}
+	f, _ := strconv.ParseFloat(number, 64)
+	return f
+}
+
+// exact conversion of number (or numeric string) to rational
+func ToRat(value any) (*big.Rat, bool) {
+	switch casted := value.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(string(casted))
+	case string:
+		return new(big.Rat).SetString(casted)
+	case int:
+		return new(big.Rat).SetInt64(int64(casted)), true
+	case int64:
+		return new(big.Rat).SetInt64(casted), true
+	case uint64:
+		return new(big.Rat).SetUint64(casted), true
+	case *big.Int:
+		return new(big.Rat).SetInt(casted), true
+	case float64:
+		rat := new(big.Rat)
+		if rat.SetFloat64(casted) == nil {
+			return nil, false
+		}
+		return rat, true
+	case bool:
+		if casted {
+			return big.NewRat(1, 1), true
+		}
+		return new(big.Rat), true
+	}
+	return nil, false
+}
+
+func numberFunc(params ...any) (any, error) {
+	if len(params) != 1 {
+		return nil, errParamNum
+	}
+
+	switch casted := params[0].(type) {
+	case int, int64, uint64, float64, *big.Int:
+		return casted, nil
+	case json.Number:
+		return convertNumber(string(casted)), nil
+	case string:
+		if _, ok := new(big.Rat).SetString(casted); !ok {
+			return nil, errNotNumber
+		}
+		return convertNumber(casted), nil
+	}
+	return nil, errNotNumber
+}
+
+func cmpNumberFunc(params ...any) (any, error) {
+	if len(params) != 2 {
+		return nil, errParamNum
+	}
+
+	rat1, ok := ToRat(params[0])
+	if !ok {
+		return nil, errNotNumber
+	}
+	rat2, ok := ToRat(params[1])
+	if !ok {
+		return nil, errNotNumber
+	}
+	return rat1.Cmp(rat2), nil
+}
+
+// operators are delegated to expr runtime, except for uint64 and big integer operands
+// (which are not handled exactly by it)
+func numberOpFunc(params ...any) (any, error) {
+	if len(params) != 3 {
+		return nil, errParamNum
+	}
+
+	operator, _ := params[0].(string)
+	left, right := params[1], params[2]
+	if isWideInt(left) || isWideInt(right) {
+		if result, ok := wideIntOp(operator, left, right); ok {
+			return result, nil
+		}
+	}
+
+	switch operator {
+	case "==":
+		return runtime.Equal(left, right), nil
+	case "!=":
+		return !runtime.Equal(left, right), nil
+	case "<":
+		return runtime.Less(left, right), nil
+	case ">":
+		return runtime.More(left, right), nil
+	case "<=":
+		return runtime.LessOrEqual(left, right), nil
+	case ">=":
+		return runtime.MoreOrEqual(left, right), nil
+	case "+":
+		return runtime.Add(left, right), nil
+	case "-":
+		return runtime.Subtract(left, right), nil
+	case "*":
+		return runtime.Multiply(left, right), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", operator)
+}
+
+func isWideInt(value any) bool {
+	switch value.(type) {
+	case uint64, *big.Int:
+		return true
+	}
+	return false
+}
+
+// exact computation when both operands are integers (float64 one is converted to float64)
+func wideIntOp(operator string, left any, right any) (any, bool) {
+	leftInt, leftOk := toBigInt(left)
+	rightInt, rightOk := toBigInt(right)
+	if !leftOk || !rightOk {
+		return nil, false
+	}
+	if leftInt == nil || rightInt == nil {
+		return numberOpFloat(operator, left, right)
+	}
+
+	switch operator {
+	case "==":
+		return leftInt.Cmp(rightInt) == 0, true
+	case "!=":
+		return leftInt.Cmp(rightInt) != 0, true
+	case "<":
+		return leftInt.Cmp(rightInt) < 0, true
+	case ">":
+		return leftInt.Cmp(rightInt) > 0, true
+	case "<=":
+		return leftInt.Cmp(rightInt) <= 0, true
+	case ">=":
+		return leftInt.Cmp(rightInt) >= 0, true
+	case "+":
+		return narrowInt(new(big.Int).Add(leftInt, rightInt)), true
+	case "-":
+		return narrowInt(new(big.Int).Sub(leftInt, rightInt)), true
+	case "*":
+		return narrowInt(new(big.Int).Mul(leftInt, rightInt)), true
+	}
+	return nil, false
+}
+
+// second result is false when value is not a number, nil big integer means a float64
+func toBigInt(value any) (*big.Int, bool) {
+	switch casted := value.(type) {
+	case int:
+		return big.NewInt(int64(casted)), true
+	case int64:
+		return big.NewInt(casted), true
+	case uint64:
+		return new(big.Int).SetUint64(casted), true
+	case *big.Int:
+		return casted, true
+	case float64:
+		return nil, true
+	}
+	return nil, false
+}
+
+func numberOpFloat(operator string, left any, right any) (any, bool) {
+	leftRat, _ := ToRat(left)
+	rightRat, _ := ToRat(right)
+	if leftRat == nil || rightRat == nil {
+		return nil, false // NaN or infinity
+	}
+
+	leftFloat, _ := leftRat.Float64()
+	rightFloat, _ := rightRat.Float64()
+	result, err := numberOpFunc(operator, leftFloat, rightFloat)
+	return result, err == nil
+}
+
+func narrowInt(bigInt *big.Int) any {
+	switch {
+	case bigInt.IsInt64():
+		return bigInt.Int64()
+	case bigInt.IsUint64():
+		return bigInt.Uint64()
+	}
+	return bigInt
+}
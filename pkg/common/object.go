@@ -0,0 +1,195 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+)
+
+var (
+	errTrailingData = errors.New("invalid character after top-level value")
+	errNotObject    = errors.New("JSON value is not an object")
+)
+
+// JSON object keeping the order of its fields
+type Object struct {
+	names  []string
+	values map[string]any
+}
+
+func NewObject(capacity int) *Object {
+	return &Object{names: make([]string, 0, capacity), values: make(map[string]any, capacity)}
+}
+
+func (o *Object) Get(name string) (any, bool) {
+	value, ok := o.values[name]
+	return value, ok
+}
+
+// a new field is added at the end, an existing one keep its position
+func (o *Object) Set(name string, value any) {
+	if _, ok := o.values[name]; !ok {
+		o.names = append(o.names, name)
+	}
+	o.values[name] = value
+}
+
+func (o *Object) Names() []string {
+	return o.names
+}
+
+func (o *Object) Len() int {
+	return len(o.names)
+}
+
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for index, name := range o.names {
+		if index != 0 {
+			buffer.WriteByte(',')
+		}
+		encodedName, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encodedName)
+		buffer.WriteByte(':')
+		encodedValue, err := json.Marshal(o.values[name])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(encodedValue)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// objects are decoded as *Object and numbers as json.Number
+func Decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return nil, errTrailingData
+	}
+	return value, nil
+}
+
+func DecodeObject(data []byte) (*Object, error) {
+	value, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	jsonObject, ok := value.(*Object)
+	if !ok {
+		return nil, errNotObject
+	}
+	return jsonObject, nil
+}
+
+func decodeValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		jsonObject := NewObject(0)
+		for decoder.More() {
+			nameToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			jsonObject.Set(nameToken.(string), value)
+		}
+		_, err = decoder.Token() // consume '}'
+		return jsonObject, err
+	case json.Delim('['):
+		array := []any{}
+		for decoder.More() {
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token() // consume ']'
+		return array, err
+	}
+	return token, nil
+}
+
+// remember field order of objects converted to map (for expression evaluation),
+// in order to restore it on expression results
+type FieldOrders map[uintptr][]string
+
+func (orders FieldOrders) record(jsonMap map[string]any, names []string) {
+	if orders != nil {
+		orders[reflect.ValueOf(jsonMap).Pointer()] = names
+	}
+}
+
+// convert recursively map to *Object, with recorded field order when known (sorted order otherwise)
+func (orders FieldOrders) Restore(value any) any {
+	switch casted := value.(type) {
+	case map[string]any:
+		names, ok := orders[reflect.ValueOf(casted).Pointer()]
+		if !ok {
+			names = make([]string, 0, len(casted))
+			for name := range casted {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+		}
+
+		jsonObject := NewObject(len(casted))
+		for _, name := range names {
+			if subValue, ok := casted[name]; ok {
+				jsonObject.Set(name, orders.Restore(subValue))
+			}
+		}
+		for name, subValue := range casted {
+			if _, ok := jsonObject.Get(name); !ok { // added after conversion
+				jsonObject.Set(name, orders.Restore(subValue))
+			}
+		}
+		return jsonObject
+	case []any:
+		restored := make([]any, len(casted))
+		for index, subValue := range casted {
+			restored[index] = orders.Restore(subValue)
+		}
+		return restored
+	}
+	return value
+}
@@ -0,0 +1,208 @@
+/*
+ *
+ * Copyright (C) 2023  Denis Vaumoron
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package common
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	errEmptySegment   = errors.New("empty segment in path")
+	errUnclosedBrace  = errors.New("unclosed '[' in path")
+	errUnclosedQuote  = errors.New("unclosed quote in path")
+	errUnexpectedChar = errors.New("unexpected character after ']' in path")
+)
+
+// a Path locate a value in nested JSON object,
+// segments are separated by '.', array index can be written as [N] (or as a simple segment),
+// quoted segments ("a.b", 'a.b', ["a.b"] or ['a.b']) are taken verbatim
+type Path struct {
+	raw      string
+	segments []string
+}
+
+// a raw path which can not be parsed (like "owner's") is taken as a literal field name
+func ParsePath(rawPath string) Path {
+	path, err := parsePath(rawPath)
+	if err != nil {
+		return LiteralPath(rawPath)
+	}
+	return path
+}
+
+func parsePath(rawPath string) (Path, error) {
+	var segments []string
+	var current strings.Builder
+	pending := false // current segment started (allow quoted empty segment)
+	runes := []rune(rawPath)
+	size := len(runes)
+	for index := 0; index < size; index++ {
+		switch c := runes[index]; c {
+		case '.':
+			if !pending || index+1 == size {
+				return Path{}, errEmptySegment
+			}
+			segments = append(segments, current.String())
+			current.Reset()
+			pending = false
+		case '"', '\'':
+			end, err := readQuoted(runes, index+1, c, &current)
+			if err != nil {
+				return Path{}, err
+			}
+			index = end
+			pending = true
+		case '[':
+			if pending {
+				segments = append(segments, current.String())
+				current.Reset()
+			}
+			end, err := readBracket(runes, index+1, &current)
+			if err != nil {
+				return Path{}, err
+			}
+			segments = append(segments, current.String())
+			current.Reset()
+			pending = false
+			index = end
+			if next := index + 1; next < size {
+				switch runes[next] {
+				case '.':
+					if next+1 == size {
+						return Path{}, errEmptySegment
+					}
+					index = next
+				case '[':
+				default:
+					return Path{}, errUnexpectedChar
+				}
+			}
+		default:
+			current.WriteRune(c)
+			pending = true
+		}
+	}
+	if pending {
+		segments = append(segments, current.String())
+	} else if len(segments) == 0 {
+		return Path{}, errEmptySegment
+	}
+	return Path{raw: rawPath, segments: segments}, nil
+}
+
+// path matching only the field with the exact name
+func LiteralPath(name string) Path {
+	return Path{raw: name, segments: []string{name}}
+}
+
+func ParsePaths(rawPaths []string) []Path {
+	paths := make([]Path, 0, len(rawPaths))
+	for _, rawPath := range rawPaths {
+		paths = append(paths, ParsePath(rawPath))
+	}
+	return paths
+}
+
+func (p Path) String() string {
+	return p.raw
+}
+
+// an existing field with the raw path as name take precedence over nested resolution
+func (p Path) Extract(jsonObject *Object) (any, bool) {
+	if value, ok := jsonObject.Get(p.raw); ok {
+		return value, true
+	}
+
+	var current any = jsonObject
+	for _, segment := range p.segments {
+		switch casted := current.(type) {
+		case *Object:
+			value, ok := casted.Get(segment)
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil {
+				return nil, false
+			}
+			if index < 0 {
+				index += len(casted)
+			}
+			if index < 0 || index >= len(casted) {
+				return nil, false
+			}
+			current = casted[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// read until closing quote (handling backslash escape), return the index of the closing quote
+func readQuoted(runes []rune, start int, quote rune, builder *strings.Builder) (int, error) {
+	for index, size := start, len(runes); index < size; index++ {
+		switch c := runes[index]; c {
+		case '\\':
+			index++
+			if index == size {
+				return 0, errUnclosedQuote
+			}
+			builder.WriteRune(runes[index])
+		case quote:
+			return index, nil
+		default:
+			builder.WriteRune(c)
+		}
+	}
+	return 0, errUnclosedQuote
+}
+
+// read an index or a quoted name, return the index of the closing brace
+func readBracket(runes []rune, start int, builder *strings.Builder) (int, error) {
+	size := len(runes)
+	if start < size && (runes[start] == '"' || runes[start] == '\'') {
+		end, err := readQuoted(runes, start+1, runes[start], builder)
+		if err != nil {
+			return 0, err
+		}
+		if end+1 >= size || runes[end+1] != ']' {
+			return 0, errUnclosedBrace
+		}
+		return end + 1, nil
+	}
+
+	for index := start; index < size; index++ {
+		c := runes[index]
+		if c != ']' {
+			builder.WriteRune(c)
+			continue
+		}
+
+		if index == start {
+			return 0, errEmptySegment
+		}
+		return index, nil
+	}
+	return 0, errUnclosedBrace
+}